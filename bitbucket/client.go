@@ -0,0 +1,171 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// APIRoot is the root of Bitbucket Cloud REST API
+const APIRoot = "https://api.bitbucket.org/2.0/"
+
+// Client of Bitbucket Cloud or Bitbucket Server (Data Center) REST API
+type Client struct {
+	restClient *resty.Client
+	server     bool
+}
+
+// NewClient returns client of Bitbucket Cloud, resty client is expected
+// to have base URL (APIRoot) and authentication set
+func NewClient(rc *resty.Client) *Client {
+	return &Client{restClient: rc}
+}
+
+// NewServerClient returns client of Bitbucket Server (Data Center), resty client is expected
+// to have base URL (https://bitbucket.example.com/rest/api/1.0/) and authentication set
+func NewServerClient(rc *resty.Client) *Client {
+	return &Client{restClient: rc, server: true}
+}
+
+func (c *Client) Req(ctx context.Context) *resty.Request {
+	return c.restClient.R().SetContext(ctx)
+}
+
+// errors of the missing entities, the 404 responses are mapped by the endpoint
+var (
+	RepositoryNotFound  = errors.New("bitbucket repository not found")
+	PullRequestNotFound = errors.New("bitbucket pull request not found")
+	CommentNotFound     = errors.New("bitbucket comment not found")
+	CommitNotFound      = errors.New("bitbucket commit not found")
+)
+
+// APIError is returned for unexpected response of Bitbucket API
+type APIError struct {
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("bitbucket call %s returned %d: %s", e.URL, e.StatusCode, e.Body)
+}
+
+// get fetches the url into the result
+func (c *Client) get(ctx context.Context, url string, result any) error {
+	return c.call(ctx, resty.MethodGet, url, nil, result)
+}
+
+// call sends the body (if any) with the method, the response is decoded into the result (if any)
+func (c *Client) call(ctx context.Context, method, url string, body, result any) error {
+	req := c.Req(ctx)
+	if body != nil {
+		req.SetBody(body)
+	}
+	if result != nil {
+		req.SetResult(result)
+	}
+	res, err := req.Execute(method, url)
+	if err != nil {
+		return fmt.Errorf("bitbucket call failed: %w", err)
+	}
+	if !res.IsSuccess() {
+		return &APIError{URL: url, StatusCode: res.StatusCode(), Body: res.String()}
+	}
+	return nil
+}
+
+// notFound returns the error of the missing entity for the 404 response of its endpoint
+func notFound(err error, entity error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", entity, apiErr.URL)
+	}
+	return err
+}
+
+// PullRequests returns open pull requests of the repository against any of the destination branches
+// (all if none given), selected by the deployment tags in comments ("deployment: tag", "deployment: no-tag"),
+// all pull requests are returned if no tags are given.
+// The fullname of the repository is workspace/slug for Cloud, PROJECTKEY/slug for Server.
+func (c *Client) PullRequests(ctx context.Context, fullname string, destinationBranches []string, tags []string) ([]PullRequest, error) {
+	if c.server {
+		return c.serverPullRequests(ctx, fullname, destinationBranches, tags)
+	}
+	return c.cloudPullRequests(ctx, fullname, destinationBranches, tags)
+}
+
+// Comment of pull request
+type Comment struct {
+	ID   int
+	Text string
+	// version of the comment, required for update by Bitbucket Server
+	Version int
+}
+
+// Comments returns top level comments of the pull request, from the oldest
+func (c *Client) Comments(ctx context.Context, fullname string, prID int) ([]Comment, error) {
+	if c.server {
+		return c.serverComments(ctx, fullname, prID)
+	}
+	return c.cloudComments(ctx, fullname, prID)
+}
+
+// commentTexts returns texts of the comments of the pull request, from the oldest
+func (c *Client) commentTexts(ctx context.Context, fullname string, prID int) ([]string, error) {
+	comments, err := c.Comments(ctx, fullname, prID)
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, len(comments))
+	for i, comment := range comments {
+		texts[i] = comment.Text
+	}
+	return texts, nil
+}
+
+// CreateComment adds a comment to the pull request
+func (c *Client) CreateComment(ctx context.Context, fullname string, prID int, text string) (*Comment, error) {
+	if c.server {
+		return c.serverCreateComment(ctx, fullname, prID, text)
+	}
+	return c.cloudCreateComment(ctx, fullname, prID, text)
+}
+
+// UpdateComment replaces the text of the comment of the pull request
+func (c *Client) UpdateComment(ctx context.Context, fullname string, prID int, comment Comment, text string) error {
+	if c.server {
+		return c.serverUpdateComment(ctx, fullname, prID, comment, text)
+	}
+	return c.cloudUpdateComment(ctx, fullname, prID, comment, text)
+}
+
+// BuildState is the state of the commit build status
+type BuildState string
+
+const (
+	BuildSuccessful BuildState = "SUCCESSFUL"
+	BuildFailed     BuildState = "FAILED"
+	BuildInProgress BuildState = "INPROGRESS"
+	// BuildStopped is reported as FAILED by Bitbucket Server
+	BuildStopped BuildState = "STOPPED"
+)
+
+// BuildStatus of a commit, the status with the same key is replaced
+type BuildStatus struct {
+	Key         string     `json:"key"`
+	State       BuildState `json:"state"`
+	Name        string     `json:"name"`
+	URL         string     `json:"url"`
+	Description string     `json:"description"`
+}
+
+// SetBuildStatus creates or replaces the build status (by its key) of the commit
+func (c *Client) SetBuildStatus(ctx context.Context, fullname, sha string, status BuildStatus) error {
+	if c.server {
+		return c.serverSetBuildStatus(ctx, fullname, sha, status)
+	}
+	return c.cloudSetBuildStatus(ctx, fullname, sha, status)
+}
@@ -0,0 +1,29 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+)
+
+func testClient(t *testing.T, handler http.Handler) *resty.Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return resty.New().SetBaseURL(srv.URL)
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Error(err)
+	}
+}
+
+func decodeJSON(t *testing.T, r *http.Request, v any) {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		t.Error(err)
+	}
+}
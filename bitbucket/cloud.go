@@ -0,0 +1,151 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/wayan/mergeexp/selection"
+)
+
+type cloudPullRequest struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	CreatedOn time.Time `json:"created_on"`
+	UpdatedOn time.Time `json:"updated_on"`
+	Links     struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+	Destination struct {
+		Branch struct {
+			Name string `json:"name"`
+		} `json:"branch"`
+	} `json:"destination"`
+	Source struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Branch struct {
+			Name string `json:"name"`
+		} `json:"branch"`
+		Commit struct {
+			Hash string `json:"hash"`
+		} `json:"commit"`
+	} `json:"source"`
+}
+
+func (c *Client) cloudPullRequests(ctx context.Context, fullname string, destinationBranches []string, tags []string) ([]PullRequest, error) {
+	// Bitbucket pull requests have no labels
+	policy := selection.Policy{Tags: tags}
+	var pullRequests []PullRequest
+
+	// paging
+	for u := "repositories/" + fullname + "/pullrequests?state=OPEN"; u != ""; {
+		var page struct {
+			Values []cloudPullRequest `json:"values"`
+			Next   string             `json:"next"`
+		}
+		if err := c.get(ctx, u, &page); err != nil {
+			return nil, fmt.Errorf("fetching pull requests: %w", notFound(err, RepositoryNotFound))
+		}
+
+		for _, cpr := range page.Values {
+			if len(destinationBranches) > 0 && !slices.Contains(destinationBranches, cpr.Destination.Branch.Name) {
+				continue
+			}
+			ok, directive, err := policy.Select(nil, func() ([]string, error) {
+				return c.commentTexts(ctx, fullname, cpr.ID)
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			pullRequests = append(pullRequests, PullRequest{
+				ID:                cpr.ID,
+				Title:             cpr.Title,
+				SourceFullname:    cpr.Source.Repository.FullName,
+				SourceBranch:      cpr.Source.Branch.Name,
+				SourceCommit:      cpr.Source.Commit.Hash,
+				DestinationBranch: cpr.Destination.Branch.Name,
+				CreatedAt:         cpr.CreatedOn,
+				UpdatedAt:         cpr.UpdatedOn,
+				Directive:         directive,
+				URL:               cpr.Links.HTML.Href,
+			})
+		}
+		u = page.Next
+	}
+	return pullRequests, nil
+}
+
+func cloudPullRequestPath(fullname string, prID int) string {
+	return fmt.Sprintf("repositories/%s/pullrequests/%d", fullname, prID)
+}
+
+type cloudComment struct {
+	ID      int  `json:"id"`
+	Deleted bool `json:"deleted"`
+	Parent  *struct {
+		ID int `json:"id"`
+	} `json:"parent"`
+	Content struct {
+		Raw string `json:"raw"`
+	} `json:"content"`
+}
+
+// cloudComments - comments are returned from the oldest
+func (c *Client) cloudComments(ctx context.Context, fullname string, prID int) ([]Comment, error) {
+	var comments []Comment
+	for u := cloudPullRequestPath(fullname, prID) + "/comments"; u != ""; {
+		var page struct {
+			Values []cloudComment `json:"values"`
+			Next   string         `json:"next"`
+		}
+		if err := c.get(ctx, u, &page); err != nil {
+			return nil, fmt.Errorf("fetching comments: %w", notFound(err, PullRequestNotFound))
+		}
+
+		for _, v := range page.Values {
+			if v.Deleted || v.Parent != nil {
+				continue
+			}
+			comments = append(comments, Comment{ID: v.ID, Text: v.Content.Raw})
+		}
+		u = page.Next
+	}
+	return comments, nil
+}
+
+func cloudCommentBody(text string) any {
+	return map[string]any{"content": map[string]string{"raw": text}}
+}
+
+func (c *Client) cloudCreateComment(ctx context.Context, fullname string, prID int, text string) (*Comment, error) {
+	var created cloudComment
+	if err := c.call(ctx, resty.MethodPost, cloudPullRequestPath(fullname, prID)+"/comments", cloudCommentBody(text), &created); err != nil {
+		return nil, fmt.Errorf("creating comment: %w", notFound(err, PullRequestNotFound))
+	}
+	return &Comment{ID: created.ID, Text: created.Content.Raw}, nil
+}
+
+func (c *Client) cloudUpdateComment(ctx context.Context, fullname string, prID int, comment Comment, text string) error {
+	u := fmt.Sprintf("%s/comments/%d", cloudPullRequestPath(fullname, prID), comment.ID)
+	if err := c.call(ctx, resty.MethodPut, u, cloudCommentBody(text), nil); err != nil {
+		return fmt.Errorf("updating comment: %w", notFound(err, CommentNotFound))
+	}
+	return nil
+}
+
+func (c *Client) cloudSetBuildStatus(ctx context.Context, fullname, sha string, status BuildStatus) error {
+	u := fmt.Sprintf("repositories/%s/commit/%s/statuses/build", fullname, sha)
+	if err := c.call(ctx, resty.MethodPost, u, status, nil); err != nil {
+		return fmt.Errorf("setting build status: %w", notFound(err, CommitNotFound))
+	}
+	return nil
+}
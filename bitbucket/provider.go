@@ -0,0 +1,135 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/wayan/mergeexp/gitdir"
+	"github.com/wayan/mergeexp/merger"
+	"github.com/wayan/mergeexp/mirror"
+	"github.com/wayan/mergeexp/provider"
+)
+
+// CloneBase is the base of ssh clone urls of Bitbucket Cloud
+const CloneBase = "git@bitbucket.org"
+
+// Provider implements provider.Provider for pull requests of the Bitbucket repository
+type Provider struct {
+	Client   *Client
+	Fullname string
+	// base of clone urls, CloneBase by default,
+	// i.e. ssh://git@bitbucket.example.com:7999 for Bitbucket Server
+	CloneBase string
+	// private ssh key used for fetching
+	DeploymentKey string
+	// the repositories are fetched through the mirrors, optional
+	Mirrors *mirror.Cache
+}
+
+var _ provider.Provider = (*Provider)(nil)
+
+func NewProvider(client *Client, fullname string) *Provider {
+	return &Provider{Client: client, Fullname: fullname}
+}
+
+// ChangeRequests returns pull requests selected by the deployment tags in comments, labels are ignored
+func (p *Provider) ChangeRequests(ctx context.Context, f provider.Filter) ([]merger.MergeRef, error) {
+	prs, err := p.Client.PullRequests(ctx, p.Fullname, f.TargetBranches, f.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []merger.MergeRef
+	for i := range prs {
+		refs = append(refs, prs[i].MergeRef())
+	}
+	return refs, nil
+}
+
+func (p *Provider) CloneURL(ctx context.Context, ref merger.MergeRef) (string, error) {
+	pr, ok := ref.(mergeRef)
+	if !ok {
+		return "", provider.ErrForeignRef
+	}
+	return p.cloneURL(pr.SourceFullname), nil
+}
+
+func (p *Provider) cloneURL(fullname string) string {
+	return CloneURL(p.CloneBase, p.Client.server, fullname)
+}
+
+// CloneURL returns the ssh clone url of the repository, base is CloneBase by default for Cloud,
+// i.e. ssh://git@bitbucket.example.com:7999 for Server
+func CloneURL(base string, server bool, fullname string) string {
+	if server {
+		return base + "/" + strings.ToLower(fullname) + ".git"
+	}
+	if base == "" {
+		base = CloneBase
+	}
+	return base + ":" + fullname + ".git"
+}
+
+// Fetch fetches the source branches from the source repositories (forks included) by URL, no remotes are added
+func (p *Provider) Fetch(ctx context.Context, dir *gitdir.Dir, refs []merger.MergeRef) error {
+	branches := map[string][]string{}
+	var repos []string
+	for _, ref := range refs {
+		pr, ok := ref.(mergeRef)
+		if !ok {
+			return provider.ErrForeignRef
+		}
+		if _, ok := branches[pr.SourceFullname]; !ok {
+			repos = append(repos, pr.SourceFullname)
+		}
+		branches[pr.SourceFullname] = append(branches[pr.SourceFullname], pr.PullRequest.SourceBranch)
+	}
+
+	for _, repo := range repos {
+		url := p.cloneURL(repo)
+		args := append([]string{"fetch", "--no-tags", url}, branches[repo]...)
+		fetchDir := dir
+		if p.DeploymentKey != "" {
+			d := *dir
+			if d.Env == nil {
+				d.Env = os.Environ()
+			}
+			d.Env = append(slices.Clip(d.Env), "GIT_SSH_COMMAND=ssh -i "+p.DeploymentKey)
+			fetchDir = &d
+		}
+		if p.Mirrors != nil {
+			if err := p.Mirrors.Fetch(ctx, fetchDir, url, branches[repo]...); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fetchDir.Run(ctx, args...); err != nil {
+			return fmt.Errorf("fetching from %s: %w", url, err)
+		}
+	}
+	for _, ref := range refs {
+		pr := ref.(mergeRef).PullRequest
+		// Bitbucket Cloud lists the abbreviated hash of the source commit
+		if dir.ShaExists(ctx, pr.SourceCommit) {
+			sha, err := dir.RevParse(ctx, pr.SourceCommit+"^{commit}")
+			if err != nil {
+				return fmt.Errorf("head of %s: %w", ref.Name(), err)
+			}
+			pr.SourceCommit = sha
+		}
+		provider.CheckPinned(ctx, dir, ref.Name(), pr.Directive, pr.SourceCommit)
+	}
+	return provider.CheckFetched(ctx, dir, refs)
+}
+
+// Feedback posts the result as a comment and a build status of the pull request, see Reporter
+func (p *Provider) Feedback(ctx context.Context, ref merger.MergeRef, fb provider.Feedback) error {
+	pr, ok := ref.(mergeRef)
+	if !ok {
+		return provider.ErrForeignRef
+	}
+	return NewReporter(p.Client, p.Fullname).Report(ctx, pr.PullRequest, fb)
+}
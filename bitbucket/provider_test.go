@@ -0,0 +1,37 @@
+package bitbucket
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wayan/mergeexp/gitdir/gitdirtest"
+	"github.com/wayan/mergeexp/merger"
+)
+
+func TestFetch(t *testing.T) {
+	origin := gitdirtest.Repo(t)
+	head := gitdirtest.Commit(t, origin, "a.txt", "1")
+	gitdirtest.Git(t, origin, "branch", "feature")
+
+	// the clone url of the Cloud repository is CloneBase:fullname.git
+	base := filepath.Join(t.TempDir(), "bitbucket")
+	if err := os.MkdirAll(base+":team", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(origin.Dir, base+":team/repo.git"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cloud lists the abbreviated hash
+	pr := &PullRequest{ID: 7, Title: "feature", SourceFullname: "team/repo", SourceBranch: "feature", SourceCommit: head[:12]}
+	p := &Provider{Client: NewClient(nil), Fullname: "team/repo", CloneBase: base}
+	dir := gitdirtest.Repo(t)
+	if err := p.Fetch(context.Background(), dir, []merger.MergeRef{pr.MergeRef()}); err != nil {
+		t.Fatal(err)
+	}
+	if pr.SourceCommit != head {
+		t.Errorf("source commit %s, want %s", pr.SourceCommit, head)
+	}
+}
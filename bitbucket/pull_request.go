@@ -0,0 +1,80 @@
+package bitbucket
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wayan/mergeexp/selection"
+)
+
+// PullRequest is the common form of Bitbucket Cloud and Server pull requests
+type PullRequest struct {
+	ID                int
+	Title             string
+	SourceFullname    string
+	SourceBranch      string
+	SourceCommit      string
+	DestinationBranch string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	// web link of the pull request
+	URL string
+	// the deployment directive the pull request was selected by, if any
+	Directive *selection.Directive
+}
+
+type mergeRef struct {
+	*PullRequest
+}
+
+func (m mergeRef) Name() string {
+	return fmt.Sprintf("PR %d: %s", m.PullRequest.ID, m.PullRequest.Title)
+}
+
+// Sha is the source commit or the commit pinned by the directive
+func (m mergeRef) Sha() string {
+	if d := m.PullRequest.Directive; d != nil && d.Sha != "" {
+		return d.Sha
+	}
+	return m.PullRequest.SourceCommit
+}
+
+func (m mergeRef) ID() int {
+	return m.PullRequest.ID
+}
+
+func (m mergeRef) CreatedAt() time.Time {
+	return m.PullRequest.CreatedAt
+}
+
+func (m mergeRef) UpdatedAt() time.Time {
+	return m.PullRequest.UpdatedAt
+}
+
+func (m mergeRef) SourceBranch() string {
+	return m.PullRequest.SourceBranch
+}
+
+func (m mergeRef) TargetBranch() string {
+	return m.PullRequest.DestinationBranch
+}
+
+// Priority is taken from the directive, 0 if there is none
+func (m mergeRef) Priority() int {
+	if d := m.PullRequest.Directive; d != nil {
+		return d.Priority
+	}
+	return 0
+}
+
+// DependsOn returns the pull requests to be merged before, from the directive
+func (m mergeRef) DependsOn() []int {
+	if d := m.PullRequest.Directive; d != nil {
+		return d.After
+	}
+	return nil
+}
+
+func (pr *PullRequest) MergeRef() mergeRef {
+	return mergeRef{PullRequest: pr}
+}
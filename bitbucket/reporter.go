@@ -0,0 +1,87 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wayan/mergeexp/provider"
+)
+
+// Reporter posts the results of the experimental build to the pull requests as a comment
+// and as a build status of the merged commit. There is a single comment per pull request
+// and experimental branch and a single build status per commit and branch, both are replaced on rebuilds.
+type Reporter struct {
+	Client   *Client
+	Fullname string
+}
+
+func NewReporter(client *Client, fullname string) *Reporter {
+	return &Reporter{Client: client, Fullname: fullname}
+}
+
+// commentMarker is the marker of the feedback, a markdown link definition as Bitbucket does not render HTML comments
+func commentMarker(branch string) string {
+	return fmt.Sprintf("[//]: # (mergeexp:%s)", branch)
+}
+
+// BuildStatusKey is the key of the build status of the experimental branch,
+// Bitbucket limits the key to 40 characters
+func BuildStatusKey(branch string) string {
+	key := "mergeexp-" + branch
+	if len(key) > 40 {
+		key = key[:40]
+	}
+	return key
+}
+
+// Report sets the build status of the merged commit in the source repository (the fork if any)
+// and creates or updates the comment of the pull request
+func (r *Reporter) Report(ctx context.Context, pr *PullRequest, fb provider.Feedback) error {
+	sha := fb.Result.SHA
+	if sha == "" {
+		sha = pr.SourceCommit
+	}
+	source := pr.SourceFullname
+	if source == "" {
+		source = r.Fullname
+	}
+	if err := r.Client.SetBuildStatus(ctx, source, sha, FeedbackStatus(pr, fb)); err != nil {
+		return err
+	}
+
+	comments, err := r.Client.Comments(ctx, r.Fullname, pr.ID)
+	if err != nil {
+		return err
+	}
+	texts := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		texts = append(texts, comment.Text)
+	}
+	return provider.PostFeedback(texts, commentMarker(fb.Branch), fb,
+		func(text string) error {
+			_, err := r.Client.CreateComment(ctx, r.Fullname, pr.ID, text)
+			return err
+		},
+		func(i int, text string) error {
+			return r.Client.UpdateComment(ctx, r.Fullname, pr.ID, comments[i], text)
+		})
+}
+
+// buildStates maps the states of the change requests onto the build states
+var buildStates = map[provider.State]BuildState{
+	provider.StateIncluded: BuildSuccessful,
+	provider.StateConflict: BuildFailed,
+	provider.StateExcluded: BuildStopped,
+}
+
+// FeedbackStatus returns the build status: in experimental build, conflict or excluded
+func FeedbackStatus(pr *PullRequest, fb provider.Feedback) BuildStatus {
+	state, description := provider.FeedbackState(fb)
+	return BuildStatus{
+		Key:         BuildStatusKey(fb.Branch),
+		Name:        "Experimental build " + fb.Branch,
+		URL:         pr.URL,
+		State:       buildStates[state],
+		Description: description,
+	}
+}
@@ -0,0 +1,131 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/wayan/mergeexp/merger"
+	"github.com/wayan/mergeexp/provider"
+)
+
+// fakeCloud serves the comments of the pull request 7 of team/repo and the build statuses, the writes are recorded
+type fakeCloud struct {
+	comments []cloudComment
+	statuses map[string]BuildStatus
+	writes   []string
+}
+
+func (f *fakeCloud) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repositories/team/repo/pullrequests/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]any{"values": f.comments})
+	})
+	mux.HandleFunc("POST /repositories/team/repo/pullrequests/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		var c cloudComment
+		decodeJSON(t, r, &c)
+		c.ID = 100 + len(f.comments)
+		f.comments = append(f.comments, c)
+		f.writes = append(f.writes, "create")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(t, w, c)
+	})
+	mux.HandleFunc("PUT /repositories/team/repo/pullrequests/7/comments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		for i := range f.comments {
+			if f.comments[i].ID == id {
+				decodeJSON(t, r, &f.comments[i])
+				f.writes = append(f.writes, "update "+r.PathValue("id"))
+				writeJSON(t, w, f.comments[i])
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("POST /repositories/{owner}/{slug}/commit/{sha}/statuses/build", func(w http.ResponseWriter, r *http.Request) {
+		var status BuildStatus
+		decodeJSON(t, r, &status)
+		f.statuses[r.PathValue("owner")+"/"+r.PathValue("slug")+"@"+r.PathValue("sha")+":"+status.Key] = status
+		w.WriteHeader(http.StatusCreated)
+	})
+	return mux
+}
+
+func cloudCommentOf(id int, text string) cloudComment {
+	var c cloudComment
+	c.ID = id
+	c.Content.Raw = text
+	return c
+}
+
+func TestCloudReporter(t *testing.T) {
+	marker := commentMarker("exp")
+	reply := cloudCommentOf(2, marker+"\n\nquoted in reply")
+	reply.Parent = &struct {
+		ID int `json:"id"`
+	}{1}
+	deleted := cloudCommentOf(3, marker+"\n\ndeleted")
+	deleted.Deleted = true
+	f := &fakeCloud{
+		comments: []cloudComment{cloudCommentOf(1, "LGTM"), reply, deleted, cloudCommentOf(4, commentMarker("other")+"\n\nfeedback of other branch")},
+		statuses: map[string]BuildStatus{},
+	}
+	r := NewReporter(NewClient(testClient(t, f.handler(t))), "team/repo")
+	pr := &PullRequest{ID: 7, SourceFullname: "fork/repo", SourceCommit: "abc", URL: "https://bitbucket.org/team/repo/pull-requests/7"}
+	ctx := context.Background()
+
+	merged := provider.Feedback{Branch: "exp", Commit: "c1", Result: merger.RefReport{SHA: "abc", Outcome: merger.OutcomeMerged}}
+	skipped := provider.Feedback{Branch: "exp", Commit: "c2", Result: merger.RefReport{SHA: "abc", Outcome: merger.OutcomeSkipped, ConflictPaths: []string{"a.txt"}}}
+	for _, fb := range []provider.Feedback{merged, merged, skipped} {
+		if err := r.Report(ctx, pr, fb); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// replies and deleted comments are never edited
+	if got := strings.Join(f.writes, ", "); got != "create, update 104" {
+		t.Errorf("writes %q, want create, then update of the created comment", got)
+	}
+	if got, want := f.comments[4].Content.Raw, provider.FeedbackText(marker, skipped); got != want {
+		t.Errorf("comment = %q, want %q", got, want)
+	}
+
+	// the status is set in the fork and replaced by its key
+	want := BuildStatus{
+		Key:         "mergeexp-exp",
+		State:       BuildFailed,
+		Name:        "Experimental build exp",
+		URL:         pr.URL,
+		Description: "conflict: a.txt",
+	}
+	if len(f.statuses) != 1 || f.statuses["fork/repo@abc:mergeexp-exp"] != want {
+		t.Errorf("statuses = %+v, want %+v", f.statuses, want)
+	}
+}
+
+func TestServerBuildStatus(t *testing.T) {
+	var got map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /projects/PRJ/repos/repo/commits/abc/builds", func(w http.ResponseWriter, r *http.Request) {
+		decodeJSON(t, r, &got)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := NewServerClient(testClient(t, mux))
+
+	// Server has no STOPPED state
+	if err := c.SetBuildStatus(context.Background(), "PRJ/repo", "abc", BuildStatus{Key: "k", State: BuildStopped}); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got["state"]) != string(BuildFailed) {
+		t.Errorf("state = %v, want FAILED", got["state"])
+	}
+	err := c.SetBuildStatus(context.Background(), "PRJ/repo", "def", BuildStatus{Key: "k", State: BuildSuccessful})
+	if !errors.Is(err, CommitNotFound) {
+		t.Errorf("error = %v, want CommitNotFound", err)
+	}
+}
@@ -0,0 +1,198 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/wayan/mergeexp/selection"
+)
+
+type serverRef struct {
+	DisplayID    string `json:"displayId"`
+	LatestCommit string `json:"latestCommit"`
+	Repository   struct {
+		Slug    string `json:"slug"`
+		Project struct {
+			Key string `json:"key"`
+		} `json:"project"`
+	} `json:"repository"`
+}
+
+type serverPullRequest struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	CreatedDate int64     `json:"createdDate"`
+	UpdatedDate int64     `json:"updatedDate"`
+	FromRef     serverRef `json:"fromRef"`
+	ToRef       serverRef `json:"toRef"`
+	Links       struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+// serverPage is the paging envelope of Bitbucket Server API
+type serverPage struct {
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+func serverRepoPath(fullname string) (string, error) {
+	key, slug, ok := strings.Cut(fullname, "/")
+	if !ok {
+		return "", fmt.Errorf("invalid Bitbucket Server repository '%s', PROJECT/slug expected", fullname)
+	}
+	return "projects/" + url.PathEscape(key) + "/repos/" + url.PathEscape(slug), nil
+}
+
+// pagedURL adds start parameter of Bitbucket Server paging
+func pagedURL(u string, start int) string {
+	sep := "?"
+	if strings.Contains(u, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%sstart=%d", u, sep, start)
+}
+
+func (c *Client) serverPullRequests(ctx context.Context, fullname string, destinationBranches []string, tags []string) ([]PullRequest, error) {
+	repoPath, err := serverRepoPath(fullname)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bitbucket pull requests have no labels
+	policy := selection.Policy{Tags: tags}
+	var pullRequests []PullRequest
+	for start, last := 0, false; !last; {
+		var page struct {
+			serverPage
+			Values []serverPullRequest `json:"values"`
+		}
+		if err := c.get(ctx, pagedURL(repoPath+"/pull-requests?state=OPEN", start), &page); err != nil {
+			return nil, fmt.Errorf("fetching pull requests: %w", notFound(err, RepositoryNotFound))
+		}
+
+		for _, spr := range page.Values {
+			if len(destinationBranches) > 0 && !slices.Contains(destinationBranches, spr.ToRef.DisplayID) {
+				continue
+			}
+			ok, directive, err := policy.Select(nil, func() ([]string, error) {
+				return c.commentTexts(ctx, fullname, spr.ID)
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			repo := spr.FromRef.Repository
+			var link string
+			if len(spr.Links.Self) > 0 {
+				link = spr.Links.Self[0].Href
+			}
+			pullRequests = append(pullRequests, PullRequest{
+				ID:                spr.ID,
+				Title:             spr.Title,
+				SourceFullname:    repo.Project.Key + "/" + repo.Slug,
+				SourceBranch:      spr.FromRef.DisplayID,
+				SourceCommit:      spr.FromRef.LatestCommit,
+				DestinationBranch: spr.ToRef.DisplayID,
+				CreatedAt:         time.UnixMilli(spr.CreatedDate),
+				UpdatedAt:         time.UnixMilli(spr.UpdatedDate),
+				Directive:         directive,
+				URL:               link,
+			})
+		}
+		start, last = page.NextPageStart, page.IsLastPage || len(page.Values) == 0
+	}
+	return pullRequests, nil
+}
+
+type serverComment struct {
+	ID      int    `json:"id"`
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+}
+
+// serverComments reads the comments from the activities, which are returned from the newest
+func (c *Client) serverComments(ctx context.Context, fullname string, prID int) ([]Comment, error) {
+	repoPath, err := serverRepoPath(fullname)
+	if err != nil {
+		return nil, err
+	}
+	activitiesURL := fmt.Sprintf("%s/pull-requests/%d/activities", repoPath, prID)
+
+	var comments []Comment
+	for start, last := 0, false; !last; {
+		var page struct {
+			serverPage
+			Values []struct {
+				Action        string        `json:"action"`
+				CommentAction string        `json:"commentAction"`
+				Comment       serverComment `json:"comment"`
+			} `json:"values"`
+		}
+		if err := c.get(ctx, pagedURL(activitiesURL, start), &page); err != nil {
+			return nil, fmt.Errorf("fetching activities: %w", notFound(err, PullRequestNotFound))
+		}
+
+		for _, a := range page.Values {
+			// edits of the comments are activities too
+			if a.Action != "COMMENTED" || (a.CommentAction != "" && a.CommentAction != "ADDED") {
+				continue
+			}
+			comments = append(comments, Comment{ID: a.Comment.ID, Text: a.Comment.Text, Version: a.Comment.Version})
+		}
+		start, last = page.NextPageStart, page.IsLastPage || len(page.Values) == 0
+	}
+	slices.Reverse(comments)
+	return comments, nil
+}
+
+func (c *Client) serverCreateComment(ctx context.Context, fullname string, prID int, text string) (*Comment, error) {
+	repoPath, err := serverRepoPath(fullname)
+	if err != nil {
+		return nil, err
+	}
+	var created serverComment
+	u := fmt.Sprintf("%s/pull-requests/%d/comments", repoPath, prID)
+	if err := c.call(ctx, resty.MethodPost, u, map[string]string{"text": text}, &created); err != nil {
+		return nil, fmt.Errorf("creating comment: %w", notFound(err, PullRequestNotFound))
+	}
+	return &Comment{ID: created.ID, Text: created.Text, Version: created.Version}, nil
+}
+
+func (c *Client) serverUpdateComment(ctx context.Context, fullname string, prID int, comment Comment, text string) error {
+	repoPath, err := serverRepoPath(fullname)
+	if err != nil {
+		return err
+	}
+	u := fmt.Sprintf("%s/pull-requests/%d/comments/%d", repoPath, prID, comment.ID)
+	body := map[string]any{"text": text, "version": comment.Version}
+	if err := c.call(ctx, resty.MethodPut, u, body, nil); err != nil {
+		return fmt.Errorf("updating comment: %w", notFound(err, CommentNotFound))
+	}
+	return nil
+}
+
+// serverSetBuildStatus uses the repository builds API (Bitbucket Server 7.4+)
+func (c *Client) serverSetBuildStatus(ctx context.Context, fullname, sha string, status BuildStatus) error {
+	repoPath, err := serverRepoPath(fullname)
+	if err != nil {
+		return err
+	}
+	if status.State == BuildStopped {
+		status.State = BuildFailed
+	}
+	u := fmt.Sprintf("%s/commits/%s/builds", repoPath, sha)
+	if err := c.call(ctx, resty.MethodPost, u, status, nil); err != nil {
+		return fmt.Errorf("setting build status: %w", notFound(err, CommitNotFound))
+	}
+	return nil
+}
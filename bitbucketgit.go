@@ -2,14 +2,14 @@ package mergeexp
 
 import (
 	"fmt"
+	"os"
 	//"log"
 	"regexp"
-)
 
-const BitBucketCloneBase = "git@bitbucket.org"
+	"github.com/wayan/mergeexp/bitbucket"
+)
 
-//const APIRoot    = "https://api.bitbucket.org/2.0/"
-// repositories/gudang/gts-ocp/pullrequests?state=OPEN"
+const BitBucketCloneBase = bitbucket.CloneBase
 
 /**/
 type BitBucketGit struct{ *MergeExp }
@@ -19,11 +19,7 @@ func (me *MergeExp) BitBucketGit() *BitBucketGit {
 }
 
 func (bb *BitBucketGit) CloneUrl(fullname string) string {
-	base := bb.BitBucketCloneBase
-	if base == "" {
-		base = BitBucketCloneBase
-	}
-	return base + ":" + fullname + ".git"
+	return bitbucket.CloneURL(bb.BitBucketCloneBase, bb.BitBucketServer, fullname)
 }
 
 func (bb *BitBucketGit) RemoteSuggestion(fullname string) string {
@@ -31,11 +27,11 @@ func (bb *BitBucketGit) RemoteSuggestion(fullname string) string {
 }
 
 func (bb *BitBucketGit) Fetch(remote string) error {
-	c := bb.Command("git", "fetch", "--prune", remote)
+	dir := bb.GitDir()
 	if bb.BitBucketDeploymentKey != "" {
-		c.Env = []string{"GIT_SSH_COMMAND=ssh -i " + bb.BitBucketDeploymentKey}
+		dir.Env = append(os.Environ(), "GIT_SSH_COMMAND=ssh -i "+bb.BitBucketDeploymentKey)
 	}
-	return c.Run()
+	return bb.GitRemotes().Fetch(dir, remote)
 }
 
 /* fetches branches from pull requests */
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/wayan/mergeexp/config"
+	"github.com/wayan/mergeexp/gitdir"
+	"github.com/wayan/mergeexp/merger"
+	"github.com/wayan/mergeexp/provider"
+)
+
+func runBuild(ctx context.Context, args []string) error {
+	var o options
+	var reportFile string
+	var noFetch bool
+	var feedback bool
+
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	o.register(fs)
+	o.registerGitlab(fs)
+	o.registerBase(fs)
+	o.registerBuild(fs)
+	fs.StringVar(&reportFile, "report", "", "write JSON report of the merge into the file")
+	fs.BoolVar(&noFetch, "no-fetch", false, "do not fetch the remote before the build")
+	fs.BoolVar(&feedback, "feedback", false, "post the results to the merge requests")
+	fs.BoolVar(&o.removeWorktree, "remove-worktree", false, "remove the worktree after the successful build (the branch is kept)")
+	fs.Parse(args)
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+
+	e, err := o.load(true)
+	if err != nil {
+		return err
+	}
+
+	dir, err := o.gitDir(e)
+	if err != nil {
+		return err
+	}
+
+	refs, err := allRefs(ctx, dir, e, noFetch)
+	if err != nil {
+		return err
+	}
+
+	var prev *merger.MergeReport
+	if e.Incremental {
+		if prev, err = previousReport(ctx, dir, merger.New(dir), e, noFetch); err != nil {
+			return err
+		}
+	}
+
+	work, err := o.startBranch(ctx, dir, e)
+	if err != nil {
+		return err
+	}
+
+	m, err := newMerger(work, e)
+	if err != nil {
+		return err
+	}
+
+	report, mergeErr := m.MergeIncremental(ctx, prev, refs)
+
+	if reportFile != "" {
+		if err := writeReport(reportFile, report); err != nil {
+			return err
+		}
+	}
+	if mergeErr != nil {
+		return mergeErr
+	}
+
+	message, err := e.RenderCommitMessage(report)
+	if err != nil {
+		return err
+	}
+	if err := m.FinalCommit(ctx, message, report); err != nil {
+		return err
+	}
+	if feedback {
+		if err := postFeedback(ctx, work, e, refs, report); err != nil {
+			return err
+		}
+	}
+	if o.removeWorktree && work != dir {
+		// kept on failure to resume the build
+		return dir.RemoveWorktree(ctx, work.Dir)
+	}
+	return nil
+}
+
+// postFeedback posts the results of the build to the merge requests, failures are only logged
+func postFeedback(ctx context.Context, dir *gitdir.Dir, e *config.Experiment, refs []merger.MergeRef, report *merger.MergeReport) error {
+	p, err := newProvider(e)
+	if err != nil {
+		return err
+	}
+	commit, err := dir.RevParse(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+
+	results := map[string]merger.RefReport{}
+	for _, rr := range report.Refs {
+		results[rr.Name] = rr
+	}
+	for _, ref := range refs {
+		rr, ok := results[ref.Name()]
+		if !ok {
+			continue
+		}
+		err := p.Feedback(ctx, ref, provider.Feedback{Branch: e.Branch, Commit: commit, Result: rr})
+		switch {
+		case errors.Is(err, provider.ErrNotSupported):
+			slog.Warn("Feedback is not supported by the provider")
+			return nil
+		case errors.Is(err, provider.ErrForeignRef):
+			// extra branches
+		case err != nil:
+			slog.Warn(fmt.Sprintf("Cannot post feedback to %s", ref.Name()), "error", err)
+		}
+	}
+	return nil
+}
+
+// previousReport loads the report of the previous build from the local or the remote branch,
+// the notes are looked up in the local notes and in the notes fetched from the remote
+func previousReport(ctx context.Context, dir *gitdir.Dir, m *merger.Merger, e *config.Experiment, noFetch bool) (*merger.MergeReport, error) {
+	remoteNotes := merger.RemoteNotesRef(e.Remote)
+	if !noFetch {
+		// notes may not exist on the remote yet, the local ones (not pushed) are not overwritten
+		if _, err := dir.Run(ctx, "fetch", e.Remote, "+"+merger.NotesRef+":"+remoteNotes); err != nil {
+			slog.Warn(fmt.Sprintf("Cannot fetch %s from %s", merger.NotesRef, e.Remote), "error", err)
+		}
+	}
+
+	for _, rev := range []string{e.Branch, e.Remote + "/" + e.Branch} {
+		for _, notes := range []string{merger.NotesRef, remoteNotes} {
+			prev, err := m.LoadReportFrom(ctx, notes, rev)
+			if err != nil || prev != nil {
+				return prev, err
+			}
+		}
+	}
+	return nil, nil
+}
+
+func writeReport(fileName string, report *merger.MergeReport) error {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+	if err := os.WriteFile(fileName, b, 0o644); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+)
+
+func runList(ctx context.Context, args []string) error {
+	var o options
+
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	o.register(fs)
+	o.registerGitlab(fs)
+	fs.Parse(args)
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+
+	e, err := o.load(true)
+	if err != nil {
+		return err
+	}
+
+	p, err := newProvider(e)
+	if err != nil {
+		return err
+	}
+	refs, err := mergeRefs(ctx, p, e)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		fmt.Printf("%s %s\n", ref.Sha(), ref.Name())
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Command mergeexp builds experimental branches by merging open merge requests
+// on top of a base branch.
+//
+// Usage:
+//
+//	mergeexp <command> [flags]
+//
+// Commands:
+//
+//	build   start the experimental branch from the base, merge the merge requests, make the final commit
+//	list    show the merge requests which would be merged
+//	plan    predict conflicts of the build without touching the working tree
+//	matrix  show pairwise conflicts between the merge requests
+//	resume  continue the interrupted build
+//	push    push the experimental branch to the remote
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/wayan/mergeexp/gitdir"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"build", "start the experimental branch, merge the merge requests, make the final commit", runBuild},
+	{"list", "show the merge requests which would be merged", runList},
+	{"plan", "predict conflicts of the build without touching the working tree", runPlan},
+	{"matrix", "show pairwise conflicts between the merge requests", runMatrix},
+	{"resume", "continue the interrupted build", runResume},
+	{"push", "push the experimental branch to the remote", runPush},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nrun '%s <command> -h' for the flags of the command\n", os.Args[0])
+}
+
+// interruptContext is cancelled by SIGTERM or Ctrl-C, which aborts the running git command
+// and stops the build, which can be resumed. Ctrl-C pressed in the conflict shell belongs to the shell.
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if sig == os.Interrupt && gitdir.ShellRunning() {
+					continue
+				}
+				cancel()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	name := os.Args[1]
+	for _, c := range commands {
+		if c.name == name {
+			if err := c.run(ctx, os.Args[2:]); err != nil {
+				slog.Error(fmt.Sprintf("%s failed", name), "error", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	if name != "-h" && name != "--help" && name != "help" {
+		fmt.Fprintf(os.Stderr, "unknown command '%s'\n\n", name)
+	}
+	usage()
+	os.Exit(2)
+}
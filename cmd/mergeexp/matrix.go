@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/wayan/mergeexp/merger"
+)
+
+func runMatrix(ctx context.Context, args []string) error {
+	var o options
+	var format string
+	var noFetch bool
+
+	fs := flag.NewFlagSet("matrix", flag.ExitOnError)
+	o.register(fs)
+	o.registerGitlab(fs)
+	o.registerBase(fs)
+	fs.StringVar(&format, "format", "text", "output format: text, json or html")
+	fs.BoolVar(&noFetch, "no-fetch", false, "do not fetch the remote before the analysis")
+	fs.Parse(args)
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+
+	e, err := o.load(true)
+	if err != nil {
+		return err
+	}
+
+	dir, err := o.gitDir(e)
+	if err != nil {
+		return err
+	}
+
+	refs, err := allRefs(ctx, dir, e, noFetch)
+	if err != nil {
+		return err
+	}
+
+	cm, err := merger.New(dir).ConflictMatrix(ctx, e.Base, refs)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "text":
+		return cm.WriteText(os.Stdout)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cm)
+	case "html":
+		return cm.WriteHTML(os.Stdout)
+	}
+	return fmt.Errorf("unknown format '%s'", format)
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wayan/mergeexp/config"
+	"github.com/wayan/mergeexp/gitdir"
+	"github.com/wayan/mergeexp/merger"
+	"github.com/wayan/mergeexp/provider"
+)
+
+// stringsFlag is a repeatable string flag
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// options shared by all commands, either loaded from the config file
+// or built from the flags
+type options struct {
+	configFile string
+	experiment string
+	timeout    time.Duration
+	trace      bool
+	gogit      bool
+
+	worktree       bool
+	worktreeDir    string
+	removeWorktree bool
+
+	flags   config.Experiment
+	gitlab  config.GitLab
+	targets stringsFlag
+	labels  stringsFlag
+	tags    stringsFlag
+	extra   stringsFlag
+}
+
+func envDefault(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.configFile, "config", "", "configuration file, when given the flags below are ignored")
+	fs.StringVar(&o.experiment, "experiment", "", "name of the experiment from the configuration file")
+	fs.StringVar(&o.flags.Dir, "dir", ".", "git working tree")
+	fs.StringVar(&o.flags.Remote, "remote", config.DefaultRemote, "remote to fetch the base from and to push to")
+	fs.StringVar(&o.flags.Branch, "branch", config.DefaultBranch, "name of the experimental branch")
+	fs.BoolVar(&o.trace, "trace", false, "log every git invocation")
+	fs.BoolVar(&o.gogit, "gogit", false, "read the repository in-process instead of running git (writes still run git)")
+	fs.DurationVar(&o.timeout, "timeout", 0, "abort the command after the duration, e.g. 10m (no limit by default)")
+}
+
+// withTimeout applies the timeout option to the context
+func (o *options) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, o.timeout)
+}
+
+func (o *options) registerGitlab(fs *flag.FlagSet) {
+	fs.StringVar(&o.gitlab.URL, "gitlab-url", envDefault("GITLAB_URL", ""), "GitLab API root, e.g. https://gitlab.example.com/api/v4 (env GITLAB_URL)")
+	fs.StringVar(&o.gitlab.Token, "gitlab-token", envDefault("GITLAB_TOKEN", ""), "GitLab access token (env GITLAB_TOKEN)")
+	fs.IntVar(&o.gitlab.Project, "project", 0, "GitLab ID of the target project")
+	fs.Var(&o.targets, "target-branch", "only merge requests targeting this branch (repeatable)")
+	fs.Var(&o.labels, "label", "only merge requests with the label (repeatable)")
+	fs.Var(&o.tags, "tag", "or merge requests with the latest \"deployment: tag\" note (repeatable)")
+}
+
+func (o *options) registerBase(fs *flag.FlagSet) {
+	fs.StringVar(&o.flags.Base, "base", "origin/master", "commit the experimental branch starts from")
+	fs.Var(&o.extra, "extra-branch", "revision merged after the merge requests (repeatable)")
+	fs.BoolVar(&o.flags.Conflict.Bisect, "bisect", false, "find the merge requests causing the conflicts")
+	fs.StringVar(&o.flags.Order, "order", "", "order of merging: id, created, updated, priority, dependency or conflicts")
+	fs.StringVar(&o.flags.Mirrors.Dir, "mirror-dir", "", "fetch the merge requests through bare mirrors in the directory")
+	fs.BoolVar(&o.flags.Mirrors.Alternates, "mirror-alternates", false, "borrow the objects of the mirrors instead of copying them")
+}
+
+func (o *options) registerBuild(fs *flag.FlagSet) {
+	fs.StringVar(&o.flags.Conflict.Policy, "conflict", "interactive", "conflict policy: interactive, skip or fail")
+	fs.IntVar(&o.flags.Conflict.Retries, "retries", 3, "number of attempts to resolve the conflict interactively")
+	fs.StringVar(&o.flags.CommitMessage, "message", config.DefaultCommitMessage, "template of the final commit message")
+	fs.BoolVar(&o.flags.Incremental, "incremental", false, "reuse the merges of the previous build of the branch")
+	fs.BoolVar(&o.worktree, "worktree", false, "build in a dedicated git worktree, the working tree is left untouched")
+	fs.StringVar(&o.worktreeDir, "worktree-dir", "", "location of the worktree (default in the user cache dir)")
+}
+
+// load returns the experiment from the config file or from the flags,
+// the provider is required for the commands dealing with merge requests
+func (o *options) load(requireProvider bool) (*config.Experiment, error) {
+	if o.configFile != "" {
+		cfg, err := config.Load(o.configFile)
+		if err != nil {
+			return nil, err
+		}
+		return cfg.Experiment(o.experiment)
+	}
+
+	e := o.flags
+	if e.Base == "" {
+		// commands not merging anything do not need base
+		e.Base = "HEAD"
+	}
+	if o.gitlab != (config.GitLab{}) {
+		e.Provider.GitLab = &o.gitlab
+	}
+	e.TargetBranches = o.targets
+	e.Labels = o.labels
+	e.Tags = o.tags
+	e.ExtraBranches = o.extra
+	validate := e.ValidateLocal
+	if requireProvider {
+		validate = e.Validate
+	}
+	if err := validate(); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (o *options) gitDir(e *config.Experiment) (*gitdir.Dir, error) {
+	dir, err := gitdir.New(e.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if o.trace {
+		dir.Hook = gitdir.SlogHook(slog.Default(), slog.LevelInfo)
+	}
+	if err := o.setBackend(dir); err != nil {
+		return nil, err
+	}
+	return dir, nil
+}
+
+func (o *options) setBackend(dir *gitdir.Dir) error {
+	if !o.gogit {
+		return nil
+	}
+	r, err := gitdir.NewGoGitReader(dir.Dir)
+	if err != nil {
+		return err
+	}
+	dir.Backend = gitdir.Split{Reader: r, Writer: &gitdir.ExecBackend{Dir: dir}}
+	return nil
+}
+
+func (o *options) worktreePath(dir *gitdir.Dir, e *config.Experiment) (string, error) {
+	if o.worktreeDir != "" {
+		return o.worktreeDir, nil
+	}
+	return dir.DefaultWorktreePath(e.Branch)
+}
+
+// startBranch starts the experimental branch from the base either in the working tree
+// or in the worktree and returns the directory where the merges are made
+func (o *options) startBranch(ctx context.Context, dir *gitdir.Dir, e *config.Experiment) (*gitdir.Dir, error) {
+	if !o.worktree {
+		if err := dir.StartExperimentalBranch(ctx, e.Branch, e.Base); err != nil {
+			return nil, err
+		}
+		return dir, nil
+	}
+
+	path, err := o.worktreePath(dir, e)
+	if err != nil {
+		return nil, err
+	}
+	wt, err := dir.Worktree(ctx, path, e.Branch, e.Base)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info(fmt.Sprintf("Building %s in worktree %s", e.Branch, wt.Dir))
+	return wt, o.setBackend(wt)
+}
+
+// workDir returns the directory of the started build, the working tree or the worktree
+func (o *options) workDir(ctx context.Context, dir *gitdir.Dir, e *config.Experiment) (*gitdir.Dir, error) {
+	if !o.worktree {
+		return dir, nil
+	}
+
+	path, err := o.worktreePath(dir, e)
+	if err != nil {
+		return nil, err
+	}
+	wt, err := dir.OpenWorktree(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if wt == nil {
+		return nil, fmt.Errorf("no worktree at %s, nothing to resume", path)
+	}
+	return wt, o.setBackend(wt)
+}
+
+// mergeRefs returns the merge (pull) requests to be merged
+func mergeRefs(ctx context.Context, p provider.Provider, e *config.Experiment) ([]merger.MergeRef, error) {
+	refs, err := p.ChangeRequests(ctx, provider.Filter{
+		TargetBranches: e.TargetBranches,
+		Labels:         e.Labels,
+		Tags:           e.Tags,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing merge requests: %w", err)
+	}
+	return refs, nil
+}
+
+// extraRefs resolves the extra branches of the experiment
+func extraRefs(ctx context.Context, dir *gitdir.Dir, e *config.Experiment) ([]merger.MergeRef, error) {
+	var refs []merger.MergeRef
+	for _, name := range e.ExtraBranches {
+		sha, err := dir.RevParse(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, revRef{name: name, sha: sha})
+	}
+	return refs, nil
+}
+
+// allRefs returns the merge requests followed by the extra branches,
+// the remote is fetched first unless noFetch is set
+func allRefs(ctx context.Context, dir *gitdir.Dir, e *config.Experiment, noFetch bool) ([]merger.MergeRef, error) {
+	p, err := newProvider(e)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := mergeRefs(ctx, p, e)
+	if err != nil {
+		return nil, err
+	}
+
+	if !noFetch {
+		if _, err := dir.Run(ctx, "fetch", "--prune", e.Remote); err != nil {
+			return nil, fmt.Errorf("fetching %s: %w", e.Remote, err)
+		}
+		if err := p.Fetch(ctx, dir, refs); err != nil {
+			return nil, fmt.Errorf("fetching merge requests: %w", err)
+		}
+	}
+
+	extra, err := extraRefs(ctx, dir, e)
+	if err != nil {
+		return nil, err
+	}
+	return append(refs, extra...), nil
+}
+
+// newMerger creates the merger configured by the experiment
+func newMerger(dir *gitdir.Dir, e *config.Experiment) (*merger.Merger, error) {
+	m := merger.New(dir)
+	m.ConflictPolicy = e.ConflictPolicy()
+	if e.Conflict.Retries > 0 {
+		m.ConflictRetries = e.Conflict.Retries
+	}
+	m.Bisect = e.Conflict.Bisect
+
+	order, err := merger.NewOrderer(e.Order, m, e.Base)
+	if err != nil {
+		return nil, err
+	}
+	m.Order = order
+	return m, nil
+}
+
+// revRef is a MergeRef for an arbitrary revision
+type revRef struct {
+	name, sha string
+}
+
+func (r revRef) Name() string { return r.name }
+func (r revRef) Sha() string  { return r.sha }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+)
+
+func runPlan(ctx context.Context, args []string) error {
+	var o options
+	var asJSON, noFetch bool
+
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	o.register(fs)
+	o.registerGitlab(fs)
+	o.registerBase(fs)
+	fs.BoolVar(&asJSON, "json", false, "print the plan as JSON")
+	fs.BoolVar(&noFetch, "no-fetch", false, "do not fetch the remote before planning")
+	fs.Parse(args)
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+
+	e, err := o.load(true)
+	if err != nil {
+		return err
+	}
+
+	dir, err := o.gitDir(e)
+	if err != nil {
+		return err
+	}
+
+	refs, err := allRefs(ctx, dir, e, noFetch)
+	if err != nil {
+		return err
+	}
+
+	m, err := newMerger(dir, e)
+	if err != nil {
+		return err
+	}
+	plan, err := m.Plan(ctx, e.Base, refs)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	return plan.WriteText(os.Stdout)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/wayan/mergeexp/bitbucket"
+	"github.com/wayan/mergeexp/config"
+	"github.com/wayan/mergeexp/gitea"
+	"github.com/wayan/mergeexp/github"
+	"github.com/wayan/mergeexp/gitlab"
+	"github.com/wayan/mergeexp/mirror"
+	"github.com/wayan/mergeexp/provider"
+)
+
+// newProvider creates the provider configured by the experiment
+func newProvider(e *config.Experiment) (provider.Provider, error) {
+	switch p := e.Provider; {
+	case p.GitLab != nil:
+		rc := resty.New().
+			SetBaseURL(p.GitLab.URL).
+			SetHeader("PRIVATE-TOKEN", p.GitLab.Token)
+		gp := gitlab.NewProvider(gitlab.NewClient(rc), p.GitLab.Project)
+		gp.Mirrors = mirrors(e)
+		return gp, nil
+
+	case p.GitHub != nil:
+		rc := resty.New().
+			SetBaseURL(p.GitHub.URL).
+			SetAuthToken(p.GitHub.Token)
+		return github.NewProvider(github.NewClient(rc), p.GitHub.Repository), nil
+
+	case p.Gitea != nil:
+		rc := resty.New().
+			SetBaseURL(p.Gitea.URL).
+			SetHeader("Authorization", "token "+p.Gitea.Token)
+		client := gitea.NewClient(rc)
+		return gitea.NewProvider(client, p.Gitea.Repository), nil
+
+	case p.Bitbucket != nil:
+		bb := p.Bitbucket
+		rc := resty.New().SetBasicAuth(bb.Username, bb.Password)
+		var client *bitbucket.Client
+		if bb.Server {
+			client = bitbucket.NewServerClient(rc.SetBaseURL(bb.APIRoot))
+		} else {
+			apiRoot := bb.APIRoot
+			if apiRoot == "" {
+				apiRoot = bitbucket.APIRoot
+			}
+			client = bitbucket.NewClient(rc.SetBaseURL(apiRoot))
+		}
+		bp := bitbucket.NewProvider(client, bb.Fullname)
+		bp.CloneBase = bb.CloneBase
+		bp.DeploymentKey = bb.DeploymentKey
+		bp.Mirrors = mirrors(e)
+		return bp, nil
+	}
+	return nil, errors.New("missing provider")
+}
+
+// mirrors returns the mirror cache of the experiment, nil if not configured
+func mirrors(e *config.Experiment) *mirror.Cache {
+	if e.Mirrors.Dir == "" {
+		return nil
+	}
+	c := mirror.New(e.Mirrors.Dir)
+	c.Alternates = e.Mirrors.Alternates
+	return c
+}
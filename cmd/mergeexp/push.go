@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/wayan/mergeexp/merger"
+)
+
+func runPush(ctx context.Context, args []string) error {
+	var o options
+	var notes bool
+
+	fs := flag.NewFlagSet("push", flag.ExitOnError)
+	o.register(fs)
+	fs.BoolVar(&notes, "notes", false, "push also the notes with build reports (for incremental builds)")
+	fs.Parse(args)
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+
+	e, err := o.load(false)
+	if err != nil {
+		return err
+	}
+
+	dir, err := o.gitDir(e)
+	if err != nil {
+		return err
+	}
+
+	// the experimental branch is always rebuilt from scratch, it must be forced
+	refspecs := []string{e.Branch + ":" + e.Branch}
+	if notes {
+		refspecs = append(refspecs, merger.NotesRef+":"+merger.NotesRef)
+	}
+	res, err := dir.Run(ctx, append([]string{"push", "--force", e.Remote}, refspecs...)...)
+	if err != nil {
+		return fmt.Errorf("pushing %s to %s: %w", e.Branch, e.Remote, err)
+	}
+	// the summary of the updated refs
+	os.Stderr.Write(res.Stderr)
+	return nil
+}
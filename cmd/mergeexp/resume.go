@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+)
+
+func runResume(ctx context.Context, args []string) error {
+	var o options
+	var reportFile string
+
+	fs := flag.NewFlagSet("resume", flag.ExitOnError)
+	o.register(fs)
+	o.registerBase(fs)
+	o.registerBuild(fs)
+	fs.StringVar(&reportFile, "report", "", "write JSON report of the merge into the file")
+	fs.Parse(args)
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+
+	e, err := o.load(false)
+	if err != nil {
+		return err
+	}
+
+	dir, err := o.gitDir(e)
+	if err != nil {
+		return err
+	}
+
+	work, err := o.workDir(ctx, dir, e)
+	if err != nil {
+		return err
+	}
+
+	m, err := newMerger(work, e)
+	if err != nil {
+		return err
+	}
+	report, mergeErr := m.Resume(ctx)
+
+	if reportFile != "" {
+		if err := writeReport(reportFile, report); err != nil {
+			return err
+		}
+	}
+	if mergeErr != nil {
+		return mergeErr
+	}
+
+	message, err := e.RenderCommitMessage(report)
+	if err != nil {
+		return err
+	}
+	return m.FinalCommit(ctx, message, report)
+}
@@ -0,0 +1,153 @@
+// Package config loads the declarative description of experimental branch builds
+// from a YAML file.
+//
+// Example:
+//
+//	experiments:
+//	  - name: nightly
+//	    dir: /srv/build/app
+//	    base: origin/master
+//	    branch: experimental
+//	    provider:
+//	      gitlab:
+//	        url: https://gitlab.example.com/api/v4
+//	        token: ${GITLAB_TOKEN}
+//	        project: 42
+//	    target_branches: [master]
+//	    labels: [experimental]
+//	    extra_branches: [origin/hotfix]
+//	    conflict:
+//	      policy: skip
+//	    mirrors:
+//	      dir: /var/cache/mergeexp/mirrors
+//	    commit_message: "Experimental merge of {{ .Name }} NOTESTS"
+//
+// String values may reference environment variables as ${NAME}.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Experiments []*Experiment `yaml:"experiments"`
+}
+
+// Error is a configuration error bound to a line of the file
+type Error struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.File == "" {
+		return e.Msg
+	}
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Load reads and validates the configuration file
+func Load(fileName string) (*Config, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	return Parse(fileName, b)
+}
+
+// Parse parses and validates the configuration, fileName is used in error messages only
+func Parse(fileName string, b []byte) (*Config, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return nil, yamlError(fileName, err)
+	}
+
+	if err := interpolate(fileName, &root, os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if len(root.Content) > 0 {
+		if err := checkKnownFields(fileName, root.Content[0], reflect.TypeOf(cfg)); err != nil {
+			return nil, err
+		}
+		if err := root.Decode(&cfg); err != nil {
+			return nil, yamlError(fileName, err)
+		}
+	}
+
+	if err := cfg.validate(fileName); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Experiment returns the experiment of the name, name may be empty if there is only one experiment
+func (c *Config) Experiment(name string) (*Experiment, error) {
+	if name == "" {
+		if len(c.Experiments) != 1 {
+			return nil, fmt.Errorf("%d experiments configured, name must be given", len(c.Experiments))
+		}
+		return c.Experiments[0], nil
+	}
+	for _, e := range c.Experiments {
+		if e.Name == name {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("experiment '%s' not configured", name)
+}
+
+func (c *Config) validate(fileName string) error {
+	if len(c.Experiments) == 0 {
+		return &Error{File: fileName, Msg: "no experiments configured"}
+	}
+
+	var errs []error
+	names := map[string]bool{}
+	for _, e := range c.Experiments {
+		e.file = fileName
+		if err := e.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if e.Name != "" {
+			if names[e.Name] {
+				errs = append(errs, e.errorf("name", "duplicate experiment name '%s'", e.Name))
+			}
+			names[e.Name] = true
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// yamlError converts YAML errors to *Error when possible,
+// yaml.v3 reports lines in messages like "line 3: cannot unmarshal ..."
+func yamlError(fileName string, err error) error {
+	var te *yaml.TypeError
+	if errors.As(err, &te) {
+		var errs []error
+		for _, msg := range te.Errors {
+			errs = append(errs, lineError(fileName, msg))
+		}
+		return errors.Join(errs...)
+	}
+	return lineError(fileName, strings.TrimPrefix(err.Error(), "yaml: "))
+}
+
+func lineError(fileName, msg string) *Error {
+	var line int
+	if _, err := fmt.Sscanf(msg, "line %d:", &line); err == nil {
+		msg = strings.TrimSpace(msg[strings.Index(msg, ":")+1:])
+	}
+	return &Error{File: fileName, Line: line, Msg: msg}
+}
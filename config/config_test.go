@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadInterpolation(t *testing.T) {
+	t.Setenv("TEST_TOKEN", "secret")
+	t.Setenv("TEST_PROJECT", "42")
+	t.Setenv("TEST_HOST", "gitlab.example.com")
+	file := filepath.Join(t.TempDir(), "mergeexp.yaml")
+	err := os.WriteFile(file, []byte(`experiments:
+  - name: nightly
+    base: origin/master
+    provider:
+      gitlab:
+        url: https://${TEST_HOST}/api/v4
+        token: ${TEST_TOKEN}
+        project: ${TEST_PROJECT}
+    commit_message: "$TEST_TOKEN ${TEST_TOKEN}"
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := cfg.Experiment("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gl := e.Provider.GitLab
+	if gl.URL != "https://gitlab.example.com/api/v4" || gl.Token != "secret" || gl.Project != 42 {
+		t.Errorf("gitlab = %+v", *gl)
+	}
+	if e.CommitMessage != "$TEST_TOKEN secret" {
+		t.Errorf("commit message = %q", e.CommitMessage)
+	}
+	// defaults
+	if e.Dir != "." || e.Remote != DefaultRemote || e.Branch != DefaultBranch || e.Conflict.Policy != "interactive" {
+		t.Errorf("defaults not filled: %+v", e)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name: "missing environment variable",
+			config: `experiments:
+  - name: a
+    base: main
+    provider:
+      gitlab:
+        url: https://gitlab.example.com
+        token: ${TEST_MISSING}
+        project: 1
+`,
+			want: []string{"cfg.yaml:7: environment variable TEST_MISSING is not set"},
+		},
+		{
+			name: "syntax error",
+			config: `experiments:
+  - name: a
+    base: main: x
+`,
+			want: []string{"cfg.yaml:3: mapping values are not allowed in this context"},
+		},
+		{
+			name: "unknown keys",
+			config: `experiments:
+  - name: a
+    base: main
+    labells: [x]
+    conflict:
+      policy: skip
+      retry: 2
+`,
+			want: []string{
+				"cfg.yaml:4: unknown key 'labells'",
+				"cfg.yaml:7: unknown key 'retry'",
+			},
+		},
+		{
+			name: "type error",
+			config: `experiments:
+  - name: a
+    base: main
+    provider:
+      gitlab:
+        url: https://gitlab.example.com
+        token: x
+        project: abc
+`,
+			want: []string{"cfg.yaml:8: cannot unmarshal !!str `abc` into int"},
+		},
+		{
+			name: "invalid experiments",
+			config: `experiments:
+  - name: a
+    provider:
+      github:
+        repository: owner/repo
+    conflict:
+      policy: merge
+  - name: a
+    base: main
+    order: random
+`,
+			want: []string{
+				"cfg.yaml:2: experiment a: missing base",
+				"cfg.yaml:3: experiment a: missing github token",
+				"cfg.yaml:6: experiment a: unknown conflict policy",
+				"cfg.yaml:8: experiment a: missing provider",
+				"cfg.yaml:10: experiment a: unknown order 'random'",
+				"cfg.yaml:8: experiment a: duplicate experiment name 'a'",
+			},
+		},
+		{
+			name:   "no experiments",
+			config: "experiments: []\n",
+			want:   []string{"cfg.yaml: no experiments configured"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("cfg.yaml", []byte(tt.config))
+			if err == nil {
+				t.Fatal("no error")
+			}
+			got := strings.Split(err.Error(), "\n")
+			if len(got) != len(tt.want) {
+				t.Fatalf("errors:\n%s\nwant:\n%s", err, strings.Join(tt.want, "\n"))
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tt.want[i]) {
+					t.Errorf("error %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
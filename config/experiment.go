@@ -0,0 +1,278 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/wayan/mergeexp/github"
+	"github.com/wayan/mergeexp/merger"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultRemote        = "origin"
+	DefaultBranch        = "experimental"
+	DefaultCommitMessage = "Experimental merge"
+)
+
+// Experiment describes a single experimental branch build
+type Experiment struct {
+	Name   string `yaml:"name"`
+	Dir    string `yaml:"dir"`
+	Remote string `yaml:"remote"`
+	Base   string `yaml:"base"`
+	Branch string `yaml:"branch"`
+
+	Provider Provider `yaml:"provider"`
+
+	// filters of merge (pull) requests, selected are those with all the labels
+	// or with the latest deployment directive in comments for any of the tags (Bitbucket, Gitea, GitHub, GitLab)
+	TargetBranches []string `yaml:"target_branches"`
+	Labels         []string `yaml:"labels"`
+	Tags           []string `yaml:"tags"`
+
+	// additional revisions merged after the merge requests
+	ExtraBranches []string `yaml:"extra_branches"`
+
+	// order of merging: id, created, updated, priority, dependency or conflicts
+	Order string `yaml:"order"`
+
+	Conflict Conflict `yaml:"conflict"`
+
+	// reuse the merges of the previous build of the branch
+	Incremental bool `yaml:"incremental"`
+
+	// bare mirrors the merge requests are fetched through (GitLab, Bitbucket)
+	Mirrors Mirrors `yaml:"mirrors"`
+
+	// text/template of the final commit message, see CommitMessageData
+	CommitMessage string `yaml:"commit_message"`
+
+	file           string
+	line           int
+	lines          map[string]int
+	commitTemplate *template.Template
+}
+
+// Provider - exactly one of the providers must be set
+type Provider struct {
+	GitLab    *GitLab    `yaml:"gitlab"`
+	Bitbucket *Bitbucket `yaml:"bitbucket"`
+	GitHub    *GitHub    `yaml:"github"`
+	Gitea     *Gitea     `yaml:"gitea"`
+}
+
+func (p Provider) count() int {
+	n := 0
+	for _, set := range []bool{p.GitLab != nil, p.Bitbucket != nil, p.GitHub != nil, p.Gitea != nil} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+type GitLab struct {
+	URL     string `yaml:"url"`
+	Token   string `yaml:"token"`
+	Project int    `yaml:"project"`
+}
+
+type Bitbucket struct {
+	APIRoot       string `yaml:"api_root"`
+	CloneBase     string `yaml:"clone_base"`
+	Fullname      string `yaml:"fullname"`
+	Username      string `yaml:"username"`
+	Password      string `yaml:"password"`
+	DeploymentKey string `yaml:"deployment_key"`
+	// Bitbucket Server (Data Center), api_root and clone_base are required then
+	Server bool `yaml:"server"`
+}
+
+type GitHub struct {
+	// API root, github.APIRoot by default
+	URL        string `yaml:"url"`
+	Token      string `yaml:"token"`
+	Repository string `yaml:"repository"`
+}
+
+// Gitea (or Forgejo)
+type Gitea struct {
+	// API root, i.e. https://gitea.example.com/api/v1
+	URL        string `yaml:"url"`
+	Token      string `yaml:"token"`
+	Repository string `yaml:"repository"`
+}
+
+type Mirrors struct {
+	// cache directory of the mirrors, shared by the experiments, no mirrors if empty
+	Dir string `yaml:"dir"`
+	// borrow the objects of the mirrors (objects/info/alternates) instead of copying them,
+	// the mirrors never gc then and the working repositories break if a mirror is deleted
+	Alternates bool `yaml:"alternates"`
+}
+
+type Conflict struct {
+	Policy  string `yaml:"policy"`
+	Retries int    `yaml:"retries"`
+	// find previously merged refs causing the conflict
+	Bisect bool `yaml:"bisect"`
+}
+
+// CommitMessageData is passed to the commit message template
+type CommitMessageData struct {
+	Name   string
+	Base   string
+	Branch string
+	Report *merger.MergeReport
+}
+
+// UnmarshalYAML decodes the experiment remembering the lines of its keys for validation errors
+func (e *Experiment) UnmarshalYAML(node *yaml.Node) error {
+	type plain Experiment
+	if err := node.Decode((*plain)(e)); err != nil {
+		return err
+	}
+	e.line = node.Line
+	e.lines = map[string]int{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		e.lines[node.Content[i].Value] = node.Content[i].Line
+	}
+	return nil
+}
+
+// Validate checks the experiment and fills the defaults
+func (e *Experiment) Validate() error {
+	return e.validate(true)
+}
+
+// ValidateLocal is Validate for the operations not talking to the provider, provider is not required
+func (e *Experiment) ValidateLocal() error {
+	return e.validate(false)
+}
+
+func (e *Experiment) validate(requireProvider bool) error {
+	var errs []error
+	add := func(key string, format string, args ...any) {
+		errs = append(errs, e.errorf(key, format, args...))
+	}
+
+	if e.Dir == "" {
+		e.Dir = "."
+	}
+	if e.Remote == "" {
+		e.Remote = DefaultRemote
+	}
+	if e.Branch == "" {
+		e.Branch = DefaultBranch
+	}
+	if e.Base == "" {
+		add("base", "missing base")
+	}
+
+	switch p := e.Provider; {
+	case p.count() > 1:
+		add("provider", "more than one provider set")
+	case p.GitLab != nil:
+		if p.GitLab.URL == "" {
+			add("provider", "missing gitlab url")
+		}
+		if p.GitLab.Token == "" {
+			add("provider", "missing gitlab token")
+		}
+		if p.GitLab.Project == 0 {
+			add("provider", "missing gitlab project")
+		}
+	case p.Bitbucket != nil:
+		if p.Bitbucket.Fullname == "" {
+			add("provider", "missing bitbucket fullname")
+		}
+		if p.Bitbucket.Username == "" {
+			add("provider", "missing bitbucket username")
+		}
+		if p.Bitbucket.Password == "" {
+			add("provider", "missing bitbucket password")
+		}
+		if p.Bitbucket.Server && (p.Bitbucket.APIRoot == "" || p.Bitbucket.CloneBase == "") {
+			add("provider", "bitbucket server requires api_root and clone_base")
+		}
+	case p.GitHub != nil:
+		if p.GitHub.URL == "" {
+			p.GitHub.URL = github.APIRoot
+		}
+		if p.GitHub.Token == "" {
+			add("provider", "missing github token")
+		}
+		if p.GitHub.Repository == "" {
+			add("provider", "missing github repository")
+		}
+	case p.Gitea != nil:
+		if p.Gitea.URL == "" {
+			add("provider", "missing gitea url")
+		}
+		if p.Gitea.Token == "" {
+			add("provider", "missing gitea token")
+		}
+		if p.Gitea.Repository == "" {
+			add("provider", "missing gitea repository")
+		}
+	case requireProvider:
+		add("provider", "missing provider")
+	}
+
+	if _, err := merger.NewOrderer(e.Order, nil, ""); err != nil {
+		add("order", "%s", err)
+	}
+
+	if e.Conflict.Policy == "" {
+		e.Conflict.Policy = merger.ConflictInteractive.String()
+	}
+	if _, err := merger.ParseConflictPolicy(e.Conflict.Policy); err != nil {
+		add("conflict", "%s", err)
+	}
+	if e.Conflict.Retries < 0 {
+		add("conflict", "negative number of retries")
+	}
+
+	if e.CommitMessage == "" {
+		e.CommitMessage = DefaultCommitMessage
+	}
+	tmpl, err := template.New("commit_message").Parse(e.CommitMessage)
+	if err != nil {
+		add("commit_message", "invalid template: %s", err)
+	}
+	e.commitTemplate = tmpl
+
+	return errors.Join(errs...)
+}
+
+// ConflictPolicy returns parsed conflict policy
+func (e *Experiment) ConflictPolicy() merger.ConflictPolicy {
+	p, _ := merger.ParseConflictPolicy(e.Conflict.Policy)
+	return p
+}
+
+// RenderCommitMessage executes the commit message template
+func (e *Experiment) RenderCommitMessage(report *merger.MergeReport) (string, error) {
+	var sb strings.Builder
+	data := CommitMessageData{Name: e.Name, Base: e.Base, Branch: e.Branch, Report: report}
+	if err := e.commitTemplate.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("rendering commit message: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// errorf returns the error at the line of the key (or of the experiment if key is not present)
+func (e *Experiment) errorf(key string, format string, args ...any) error {
+	line, ok := e.lines[key]
+	if !ok {
+		line = e.line
+	}
+	msg := fmt.Sprintf(format, args...)
+	if e.Name != "" {
+		msg = fmt.Sprintf("experiment %s: %s", e.Name, msg)
+	}
+	return &Error{File: e.file, Line: line, Msg: msg}
+}
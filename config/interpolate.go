@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var envRe = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// interpolate replaces ${NAME} in all scalar values by the value of the environment variable
+func interpolate(fileName string, node *yaml.Node, lookup func(string) (string, bool)) error {
+	if node.Kind == yaml.ScalarNode {
+		var missing string
+		value := node.Value
+		node.Value = envRe.ReplaceAllStringFunc(node.Value, func(s string) string {
+			name := envRe.FindStringSubmatch(s)[1]
+			v, ok := lookup(name)
+			if !ok && missing == "" {
+				missing = name
+			}
+			return v
+		})
+		if missing != "" {
+			return &Error{File: fileName, Line: node.Line, Msg: fmt.Sprintf("environment variable %s is not set", missing)}
+		}
+		if node.Value != value && node.Style == 0 {
+			// plain scalar, its type (int, bool, ...) is resolved from the interpolated value
+			node.Tag = ""
+		}
+		return nil
+	}
+
+	for _, child := range node.Content {
+		if err := interpolate(fileName, child, lookup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkKnownFields reports keys of the mappings which do not match any field of the target struct
+func checkKnownFields(fileName string, node *yaml.Node, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		var errs []error
+		for _, child := range node.Content {
+			errs = append(errs, checkKnownFields(fileName, child, t.Elem()))
+		}
+		return errors.Join(errs...)
+
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		fields := map[string]reflect.Type{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name != "" && name != "-" {
+				fields[name] = f.Type
+			}
+		}
+
+		var errs []error
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			ft, ok := fields[key.Value]
+			if !ok {
+				errs = append(errs, &Error{File: fileName, Line: key.Line, Msg: fmt.Sprintf("unknown key '%s'", key.Value)})
+				continue
+			}
+			errs = append(errs, checkKnownFields(fileName, value, ft))
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
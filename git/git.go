@@ -1,6 +1,7 @@
 package git
 
 import (
+	"context"
 	"fmt"
 	"iter"
 	"slices"
@@ -12,14 +13,13 @@ import (
 
 // chaotic mixture of git related utilities
 
-func LsRemote(gd *gitdir.Dir, args ...string) (string, error) {
+func LsRemote(ctx context.Context, gd *gitdir.Dir, args ...string) (string, error) {
 	args = append([]string{"ls-remote"}, args...)
-	cmd := gd.Command("git", args...)
-	out, err := cmd.Output()
+	r, err := gd.Run(ctx, args...)
 	if err != nil {
 		return "", fmt.Errorf("fetching remote failed: %w", err)
 	}
-	s := string(out)
+	s := string(r.Stdout)
 	idxWhite := strings.IndexFunc(s, unicode.IsSpace)
 	if idxWhite < 0 {
 		return "", fmt.Errorf("unexpected output from ls-remote: %s", s)
@@ -47,16 +47,15 @@ func parseOutputTable(output []byte) iter.Seq[[]string] {
 	}
 }
 
-func HighestVersionTag(gd *gitdir.Dir, url string) (*VersionTag, error) {
-	cmd := gd.Command("git", "ls-remote", "--tags", "--sort=v:refname", url)
-	out, err := cmd.Output()
+func HighestVersionTag(ctx context.Context, gd *gitdir.Dir, url string) (*VersionTag, error) {
+	r, err := gd.Run(ctx, "ls-remote", "--tags", "--sort=v:refname", url)
 	if err != nil {
 		return nil, fmt.Errorf("fetching remote failed: %w", err)
 	}
 
 	// parsing the output
 	var tags []VersionTag
-	for row := range parseOutputTable(out) {
+	for row := range parseOutputTable(r.Stdout) {
 		if len(row) < 2 {
 			continue
 		}
@@ -0,0 +1,248 @@
+package gitdir
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Reader covers the read-only repository operations
+type Reader interface {
+	// CurrentBranch returns the short name of the current branch, empty when HEAD is detached
+	CurrentBranch(ctx context.Context) (string, error)
+	// Status returns the porcelain status of the working tree, empty when it is clean
+	Status(ctx context.Context) (string, error)
+	// RevParse returns the SHA of the commit of the revision
+	RevParse(ctx context.Context, rev string) (string, error)
+	// ObjectType returns the type of the object (commit, tree, blob, tag)
+	ObjectType(ctx context.Context, sha string) (string, error)
+	Remotes(ctx context.Context) ([]string, error)
+	RemoteURL(ctx context.Context, remote string) (string, error)
+	// LsRemote lists the refs of the remote repository matching the patterns (all if none given),
+	// the patterns are git globs matched against the trailing path components of the ref
+	LsRemote(ctx context.Context, url string, patterns ...string) ([]RemoteRef, error)
+	// UnmergedPaths returns the paths with unresolved conflicts, empty if there are none
+	UnmergedPaths(ctx context.Context) ([]string, error)
+	// MergeInProgress returns true if the merge stopped on conflicts is not committed yet (MERGE_HEAD exists)
+	MergeInProgress(ctx context.Context) (bool, error)
+	// IsAncestor returns true if the commit is in the history of rev (rev included)
+	IsAncestor(ctx context.Context, commit, rev string) (bool, error)
+	// Note returns the note of the revision in the notes ref, empty if there is none
+	Note(ctx context.Context, notesRef, rev string) (string, error)
+	// GitDir returns the absolute path of the git directory (of the linked worktree if it is one)
+	GitDir(ctx context.Context) (string, error)
+}
+
+// Writer covers the repository operations changing the repository or the working tree
+type Writer interface {
+	// ForceBranch creates or resets the branch to the target (git branch -f)
+	ForceBranch(ctx context.Context, branch, target string) error
+	Checkout(ctx context.Context, branch string) error
+	ResetHard(ctx context.Context, target string) error
+	// Merge merges the revision into the current branch, always creating a merge commit (--no-ff)
+	Merge(ctx context.Context, message, rev string) error
+	Commit(ctx context.Context, message string, allowEmpty bool) error
+	RemoteAdd(ctx context.Context, remote, url string) error
+	// MergeAbort returns the working tree to the state before the merge stopped on conflicts
+	MergeAbort(ctx context.Context) error
+	// AddNote attaches the note to the revision in the notes ref, replacing the previous one
+	AddNote(ctx context.Context, notesRef, rev, note string) error
+	// MergeTree merges two commits without touching the working tree or the index,
+	// the merged tree is written to the repository
+	MergeTree(ctx context.Context, ours, theirs string) (*MergeTreeResult, error)
+	// CommitTree creates a commit object of the tree, without updating any ref
+	CommitTree(ctx context.Context, tree, message string, parents ...string) (string, error)
+}
+
+// Backend performs the repository operations of Dir
+type Backend interface {
+	Reader
+	Writer
+}
+
+// RemoteRef is a ref listed by LsRemote
+type RemoteRef struct {
+	Name string
+	SHA  string
+}
+
+// Split is the backend reading by Reader and writing by Writer,
+// i.e. the in-process reads (GoGitReader) combined with ExecBackend
+type Split struct {
+	Reader
+	Writer
+}
+
+// ExecBackend runs the git binary, it is the default backend of Dir
+type ExecBackend struct {
+	Dir *Dir
+}
+
+var _ Backend = (*ExecBackend)(nil)
+
+func (b *ExecBackend) CurrentBranch(ctx context.Context) (string, error) {
+	out, err := b.Dir.Output(ctx, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		// Check if it's a "not a symbolic ref" error, which means detached HEAD
+		var gitErr *GitError
+		if errors.As(err, &gitErr) && strings.Contains(string(gitErr.Stderr), "not a symbolic ref") {
+			return "", nil // Not on a branch
+		}
+		return "", err // Other error
+	}
+	return out, nil
+}
+
+func (b *ExecBackend) Status(ctx context.Context) (string, error) {
+	return b.Dir.Output(ctx, "status", "--porcelain")
+}
+
+func (b *ExecBackend) RevParse(ctx context.Context, rev string) (string, error) {
+	return b.Dir.Output(ctx, "rev-parse", "--verify", "-q", rev+"^{commit}")
+}
+
+func (b *ExecBackend) ObjectType(ctx context.Context, sha string) (string, error) {
+	return b.Dir.Output(ctx, "cat-file", "-t", sha)
+}
+
+func (b *ExecBackend) Remotes(ctx context.Context) ([]string, error) {
+	out, err := b.Dir.Output(ctx, "remote")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+func (b *ExecBackend) RemoteURL(ctx context.Context, remote string) (string, error) {
+	return b.Dir.Output(ctx, "remote", "get-url", remote)
+}
+
+func (b *ExecBackend) LsRemote(ctx context.Context, url string, patterns ...string) ([]RemoteRef, error) {
+	out, err := b.Dir.Output(ctx, append([]string{"ls-remote", url}, patterns...)...)
+	if err != nil {
+		return nil, err
+	}
+	var refs []RemoteRef
+	for _, line := range strings.Split(out, "\n") {
+		if sha, name, ok := strings.Cut(line, "\t"); ok {
+			refs = append(refs, RemoteRef{Name: name, SHA: sha})
+		}
+	}
+	return refs, nil
+}
+
+func (b *ExecBackend) UnmergedPaths(ctx context.Context) ([]string, error) {
+	out, err := b.Dir.Output(ctx, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	// the path is listed for every stage
+	return slices.Compact(strings.Fields(out)), nil
+}
+
+func (b *ExecBackend) MergeInProgress(ctx context.Context) (bool, error) {
+	_, err := b.Dir.Run(ctx, "rev-parse", "-q", "--verify", "MERGE_HEAD")
+	if err == nil {
+		return true, nil
+	}
+	if ExitCode(err) == 1 {
+		return false, nil
+	}
+	return false, err
+}
+
+func (b *ExecBackend) IsAncestor(ctx context.Context, commit, rev string) (bool, error) {
+	_, err := b.Dir.Run(ctx, "merge-base", "--is-ancestor", commit, rev)
+	if err == nil {
+		return true, nil
+	}
+	if ExitCode(err) == 1 {
+		return false, nil
+	}
+	return false, err
+}
+
+func (b *ExecBackend) Note(ctx context.Context, notesRef, rev string) (string, error) {
+	r, err := b.Dir.Run(ctx, "notes", "--ref", notesRef, "show", rev)
+	if err != nil {
+		// exit code 1 means no note
+		if ExitCode(err) == 1 {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(r.Stdout), nil
+}
+
+func (b *ExecBackend) GitDir(ctx context.Context) (string, error) {
+	return b.Dir.Output(ctx, "rev-parse", "--absolute-git-dir")
+}
+
+func (b *ExecBackend) ForceBranch(ctx context.Context, branch, target string) error {
+	_, err := b.Dir.Run(ctx, "branch", "-f", branch, target)
+	return err
+}
+
+func (b *ExecBackend) Checkout(ctx context.Context, branch string) error {
+	_, err := b.Dir.Run(ctx, "checkout", branch)
+	return err
+}
+
+func (b *ExecBackend) ResetHard(ctx context.Context, target string) error {
+	_, err := b.Dir.Run(ctx, "reset", "--hard", target)
+	return err
+}
+
+func (b *ExecBackend) Merge(ctx context.Context, message, rev string) error {
+	_, err := b.Dir.Run(ctx, "merge", "--no-ff", "--log", "-m", message, rev)
+	return err
+}
+
+func (b *ExecBackend) Commit(ctx context.Context, message string, allowEmpty bool) error {
+	args := []string{"commit", "--message", message}
+	if allowEmpty {
+		args = append(args, "--allow-empty")
+	}
+	_, err := b.Dir.Run(ctx, args...)
+	return err
+}
+
+func (b *ExecBackend) RemoteAdd(ctx context.Context, remote, url string) error {
+	_, err := b.Dir.Run(ctx, "remote", "add", remote, url)
+	return err
+}
+
+func (b *ExecBackend) MergeAbort(ctx context.Context) error {
+	_, err := b.Dir.Run(ctx, "merge", "--abort")
+	return err
+}
+
+func (b *ExecBackend) AddNote(ctx context.Context, notesRef, rev, note string) error {
+	_, err := b.Dir.RunInput(ctx, strings.NewReader(note), "notes", "--ref", notesRef, "add", "--force", "--file", "-", rev)
+	return err
+}
+
+// MergeTree requires git 2.38 or newer
+func (b *ExecBackend) MergeTree(ctx context.Context, ours, theirs string) (*MergeTreeResult, error) {
+	r, err := b.Dir.Run(ctx, "merge-tree", "--write-tree", "--name-only", "--no-messages", ours, theirs)
+	// exit code 1 means conflicts, anything else is failure
+	if err != nil && r.ExitCode != 1 {
+		return nil, err
+	}
+	result, perr := parseMergeTree(r.String(), err != nil)
+	if perr != nil {
+		// the error of git if there is any
+		return nil, cmp.Or(err, perr)
+	}
+	return result, nil
+}
+
+func (b *ExecBackend) CommitTree(ctx context.Context, tree, message string, parents ...string) (string, error) {
+	args := []string{"commit-tree", tree, "-m", message}
+	for _, p := range parents {
+		args = append(args, "-p", p)
+	}
+	return b.Dir.Output(ctx, args...)
+}
@@ -1,18 +1,30 @@
 package gitdir
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
+	"time"
 )
 
 // Dir represents different utilities for a git working tree
 type Dir struct {
 	Dir string
 	Env []string
+	// observes the git invocations made by Run, optional
+	Hook Hook
+	// performs the repository operations, ExecBackend if nil
+	Backend Backend
+}
+
+// Git returns the backend of the repository operations
+func (wd *Dir) Git() Backend {
+	if wd.Backend != nil {
+		return wd.Backend
+	}
+	return &ExecBackend{Dir: wd}
 }
 
 func New(dirRel string) (*Dir, error) {
@@ -32,8 +44,22 @@ func New(dirRel string) (*Dir, error) {
 	return &Dir{Dir: dir}, nil
 }
 
+// CancelWaitDelay is the time the cancelled command has to exit after the interrupt before it is killed
+const CancelWaitDelay = 5 * time.Second
+
+// Command returns the command run in the working tree, it is not cancellable
 func (wd *Dir) Command(command string, args ...string) *exec.Cmd {
-	cmd := exec.Command(command, args...)
+	return wd.CommandContext(context.Background(), command, args...)
+}
+
+// CommandContext returns the command run in the working tree cancelled by the context,
+// the command is interrupted (as by Ctrl-C) first and killed after CancelWaitDelay
+func (wd *Dir) CommandContext(ctx context.Context, command string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = CancelWaitDelay
 	cmd.Dir = wd.Dir
 	cmd.Stderr = os.Stderr
 	cmd.Env = wd.Env
@@ -41,9 +67,10 @@ func (wd *Dir) Command(command string, args ...string) *exec.Cmd {
 }
 
 func (wd *Dir) GitInit() error {
-	err := wd.Command("git", "status").Run()
+	ctx := context.Background()
+	_, err := wd.Run(ctx, "status")
 	if err != nil {
-		err = wd.Command("git", "init").Run()
+		_, err = wd.Run(ctx, "init")
 	}
 	return err
 }
@@ -59,16 +86,17 @@ func (wd *Dir) GitInit() error {
 //
 // Parameters:
 //
+//	ctx: Cancels the running git command.
 //	branch: The name of the branch to create or recreate.
 //	target: The commit, branch, or tag from which to start the 'branch' (e.g., "main", "HEAD~1", "v1.0").
 //
 // Returns:
 //
 //	An error if any Git command fails, otherwise nil.
-func (wd *Dir) StartExperimentalBranch(branch, target string) error {
+func (wd *Dir) StartExperimentalBranch(ctx context.Context, branch, target string) error {
 
 	// checking the current branch first
-	currentBranch, err := wd.currentBranch()
+	currentBranch, err := wd.Git().CurrentBranch(ctx)
 	if err != nil {
 		return fmt.Errorf("check for current branch: %w", err)
 	}
@@ -78,14 +106,14 @@ func (wd *Dir) StartExperimentalBranch(branch, target string) error {
 		// WARNING: This is a dangerous operation.
 		// It resets the current branch (which is now 'branch') to the 'target' commit,
 		// discarding any local changes in the working directory and staging area.
-		if err := wd.Command("git", "reset", "--hard", target).Run(); err != nil {
+		if err := wd.Git().ResetHard(ctx, target); err != nil {
 			return fmt.Errorf("resetting to %s: %w", branch, err)
 		}
 		return nil
 	}
 
 	// checking if the working tree is not in a clean state
-	out, err := wd.Command("git", "status", "--porcelain").Output()
+	out, err := wd.Git().Status(ctx)
 	if err != nil {
 		return fmt.Errorf("checking the working tree state: %w", err)
 	}
@@ -98,40 +126,46 @@ func (wd *Dir) StartExperimentalBranch(branch, target string) error {
 
 	// Forcefully create or recreate the branch from the target.
 	// This command will overwrite 'branch' if it already exists.
-	if err := wd.Command("git", "branch", "-f", branch, target).Run(); err != nil {
+	if err := wd.Git().ForceBranch(ctx, branch, target); err != nil {
 		return fmt.Errorf("creating branch %s: %w", branch, err)
 	}
 
 	// Checkout the specified branch.
-	if err := wd.Command("git", "checkout", branch).Run(); err != nil {
+	if err := wd.Git().Checkout(ctx, branch); err != nil {
 		return fmt.Errorf("checking out %s: %w", branch, err)
 	}
 
 	return nil
 }
 
-// currentBranch returns the name of the current branch.
-// It returns an empty string and an error if not on a branch (detached HEAD) or on error.
-func (wd *Dir) currentBranch() (string, error) {
-	cmd := wd.Command("git", "symbolic-ref", "--short", "HEAD")
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+func (wd *Dir) ShaExists(ctx context.Context, sha string) bool {
+	_, err := wd.Git().ObjectType(ctx, sha)
+	return err == nil
+}
 
-	err := cmd.Run()
+// RevParse returns the SHA of the revision
+func (wd *Dir) RevParse(ctx context.Context, rev string) (string, error) {
+	out, err := wd.Git().RevParse(ctx, rev)
 	if err != nil {
-		// Check if it's a "not a symbolic ref" error, which means detached HEAD
-		if strings.Contains(stderr.String(), "not a symbolic ref") {
-			return "", nil // Not on a branch
-		}
-		return "", err // Other error
+		return "", fmt.Errorf("resolving %s: %w", rev, err)
 	}
+	return out, nil
+}
 
-	return strings.TrimSpace(stdout.String()), nil
+// GitDir returns the absolute path of the git directory (of the linked worktree if it is one)
+func (wd *Dir) GitDir(ctx context.Context) (string, error) {
+	out, err := wd.Git().GitDir(ctx)
+	if err != nil {
+		return "", fmt.Errorf("locating git dir: %w", err)
+	}
+	return out, nil
 }
 
-func (wd *Dir) ShaExists(sha string) bool {
-	cmd := wd.Command("git", "cat-file", "-t", sha)
-	cmd.Stderr = nil
-	return cmd.Run() == nil
+// IsAncestor returns true if the commit is in the history of rev (rev included)
+func (wd *Dir) IsAncestor(ctx context.Context, commit, rev string) (bool, error) {
+	ok, err := wd.Git().IsAncestor(ctx, commit, rev)
+	if err != nil {
+		return false, fmt.Errorf("checking %s is in history of %s: %w", commit, rev, err)
+	}
+	return ok, nil
 }
@@ -0,0 +1,232 @@
+// Package gitdirtest provides the in-memory gitdir.Backend for the tests of the code using gitdir.
+package gitdirtest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/wayan/mergeexp/gitdir"
+)
+
+// Backend simulates the repository by the sets of merged revisions: every commit is known
+// by the SHAs merged into it since the base. The merges conflict as decided by Conflict.
+type Backend struct {
+	// Revs resolves the revisions to SHAs, HEAD included; the unknown revisions resolve to themselves
+	Revs map[string]string
+	// Conflict returns the paths conflicting when the SHA is merged into the commit
+	// with the merged SHAs (in the order of merging), nil when the merge is clean.
+	// All the merges are clean when Conflict is nil.
+	Conflict func(merged []string, sha string) []string
+	// MergeErr fails the merge of the SHA without any conflict, i.e. unknown revision
+	MergeErr map[string]error
+	// Notes by notes ref and SHA ("refs/notes/x:sha")
+	Notes map[string]string
+	// Dir is returned by GitDir
+	Dir string
+	// Calls records the operations changing the repository, i.e. "merge sha", "merge --abort"
+	Calls []string
+
+	// the SHAs merged into the commits created by the backend and their parents
+	merged    map[string][]string
+	parents   map[string][2]string
+	unmerged  []string
+	mergeHead string
+}
+
+var _ gitdir.Backend = (*Backend)(nil)
+
+func (b *Backend) resolve(rev string) string {
+	if sha, ok := b.Revs[rev]; ok {
+		return sha
+	}
+	return rev
+}
+
+func (b *Backend) setHead(sha string) {
+	if b.Revs == nil {
+		b.Revs = map[string]string{}
+	}
+	b.Revs["HEAD"] = sha
+}
+
+// Merged returns the SHAs merged into the commit since the base
+func (b *Backend) Merged(rev string) []string {
+	return b.merged[b.resolve(rev)]
+}
+
+// commit creates the commit of the merge of sha into the parent
+func (b *Backend) commit(parent, sha string) string {
+	if b.merged == nil {
+		b.merged = map[string][]string{}
+	}
+	if b.parents == nil {
+		b.parents = map[string][2]string{}
+	}
+	merged := append(slices.Clone(b.merged[parent]), sha)
+	commit := fmt.Sprintf("%040x", len(b.merged)+1)
+	b.merged[commit] = merged
+	b.parents[commit] = [2]string{parent, sha}
+	return commit
+}
+
+func (b *Backend) conflict(parent, sha string) []string {
+	if b.Conflict == nil {
+		return nil
+	}
+	return b.Conflict(b.merged[parent], sha)
+}
+
+func (b *Backend) CurrentBranch(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+func (b *Backend) Status(ctx context.Context) (string, error) {
+	return strings.Join(b.unmerged, "\n"), nil
+}
+
+// RevParse resolves the revisions by Revs, the parents (rev^1, rev^2) of the commits created by the backend are known
+func (b *Backend) RevParse(ctx context.Context, rev string) (string, error) {
+	rev = strings.TrimSuffix(rev, "^{commit}")
+	if rev == "MERGE_HEAD" && b.mergeHead != "" {
+		return b.mergeHead, nil
+	}
+	if base, n, ok := strings.Cut(rev, "^"); ok && (n == "1" || n == "2") {
+		parents, ok := b.parents[b.resolve(base)]
+		if !ok {
+			return "", fmt.Errorf("unknown revision %s", rev)
+		}
+		return parents[n[0]-'1'], nil
+	}
+	sha, ok := b.Revs[rev]
+	if !ok {
+		if _, ok := b.merged[rev]; !ok {
+			return "", fmt.Errorf("unknown revision %s", rev)
+		}
+		sha = rev
+	}
+	return sha, nil
+}
+
+func (b *Backend) ObjectType(ctx context.Context, sha string) (string, error) {
+	if _, err := b.RevParse(ctx, sha); err != nil {
+		return "", err
+	}
+	return "commit", nil
+}
+
+func (b *Backend) Remotes(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (b *Backend) RemoteURL(ctx context.Context, remote string) (string, error) {
+	return "", fmt.Errorf("no remote %s", remote)
+}
+
+func (b *Backend) LsRemote(ctx context.Context, url string, patterns ...string) ([]gitdir.RemoteRef, error) {
+	return nil, nil
+}
+
+func (b *Backend) UnmergedPaths(ctx context.Context) ([]string, error) {
+	return b.unmerged, nil
+}
+
+func (b *Backend) MergeInProgress(ctx context.Context) (bool, error) {
+	return b.mergeHead != "", nil
+}
+
+func (b *Backend) IsAncestor(ctx context.Context, commit, rev string) (bool, error) {
+	commit, rev = b.resolve(commit), b.resolve(rev)
+	return commit == rev || slices.Contains(b.merged[rev], commit), nil
+}
+
+func (b *Backend) Note(ctx context.Context, notesRef, rev string) (string, error) {
+	return b.Notes[notesRef+":"+b.resolve(rev)], nil
+}
+
+func (b *Backend) GitDir(ctx context.Context) (string, error) {
+	return b.Dir, nil
+}
+
+func (b *Backend) ForceBranch(ctx context.Context, branch, target string) error {
+	b.Calls = append(b.Calls, "branch -f "+branch+" "+target)
+	return nil
+}
+
+func (b *Backend) Checkout(ctx context.Context, branch string) error {
+	b.Calls = append(b.Calls, "checkout "+branch)
+	return nil
+}
+
+func (b *Backend) ResetHard(ctx context.Context, target string) error {
+	b.Calls = append(b.Calls, "reset --hard "+target)
+	b.setHead(b.resolve(target))
+	return nil
+}
+
+// Merge stops on the conflict leaving the unmerged paths and MERGE_HEAD, as git does
+func (b *Backend) Merge(ctx context.Context, message, rev string) error {
+	sha := b.resolve(rev)
+	b.Calls = append(b.Calls, "merge "+sha)
+	if err := b.MergeErr[sha]; err != nil {
+		return err
+	}
+	head := b.resolve("HEAD")
+	if paths := b.conflict(head, sha); len(paths) > 0 {
+		b.unmerged, b.mergeHead = paths, sha
+		return fmt.Errorf("merge of %s conflicts", sha)
+	}
+	b.setHead(b.commit(head, sha))
+	return nil
+}
+
+// Commit concludes the merge in progress, the unmerged paths are taken as resolved
+func (b *Backend) Commit(ctx context.Context, message string, allowEmpty bool) error {
+	b.Calls = append(b.Calls, "commit")
+	if b.mergeHead == "" {
+		return nil
+	}
+	b.setHead(b.commit(b.resolve("HEAD"), b.mergeHead))
+	b.unmerged, b.mergeHead = nil, ""
+	return nil
+}
+
+func (b *Backend) RemoteAdd(ctx context.Context, remote, url string) error {
+	b.Calls = append(b.Calls, "remote add "+remote+" "+url)
+	return nil
+}
+
+func (b *Backend) MergeAbort(ctx context.Context) error {
+	b.Calls = append(b.Calls, "merge --abort")
+	if b.mergeHead == "" {
+		return fmt.Errorf("there is no merge to abort")
+	}
+	b.unmerged, b.mergeHead = nil, ""
+	return nil
+}
+
+func (b *Backend) AddNote(ctx context.Context, notesRef, rev, note string) error {
+	if b.Notes == nil {
+		b.Notes = map[string]string{}
+	}
+	b.Notes[notesRef+":"+b.resolve(rev)] = note
+	return nil
+}
+
+// MergeTree returns the tree named after the commits, to be committed by CommitTree
+func (b *Backend) MergeTree(ctx context.Context, ours, theirs string) (*gitdir.MergeTreeResult, error) {
+	ours, theirs = b.resolve(ours), b.resolve(theirs)
+	return &gitdir.MergeTreeResult{
+		Tree:      ours + "+" + theirs,
+		Conflicts: b.conflict(ours, theirs),
+	}, nil
+}
+
+func (b *Backend) CommitTree(ctx context.Context, tree, message string, parents ...string) (string, error) {
+	ours, theirs, ok := strings.Cut(tree, "+")
+	if !ok {
+		return "", fmt.Errorf("unknown tree %s", tree)
+	}
+	return b.commit(ours, theirs), nil
+}
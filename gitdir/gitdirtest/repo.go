@@ -0,0 +1,51 @@
+package gitdirtest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/wayan/mergeexp/gitdir"
+)
+
+// Repo returns a new repository with an empty main branch in a temporary directory,
+// git runs isolated from the configuration of the user and the system.
+// The test is skipped when git is not installed.
+func Repo(t testing.TB) *gitdir.Dir {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	wd := &gitdir.Dir{Dir: t.TempDir(), Env: []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + t.TempDir(),
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	}}
+	Git(t, wd, "init", "-q", "-b", "main")
+	return wd
+}
+
+// Git runs git in the repository and returns its trimmed output, the test fails on error
+func Git(t testing.TB, wd *gitdir.Dir, args ...string) string {
+	t.Helper()
+	res, err := wd.Run(context.Background(), args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.String()
+}
+
+// Commit writes the file and commits it on the current branch, the SHA of the commit is returned
+func Commit(t testing.TB, wd *gitdir.Dir, path, content string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(wd.Dir, path), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	Git(t, wd, "add", path)
+	Git(t, wd, "commit", "-q", "-m", path+": "+content)
+	return Git(t, wd, "rev-parse", "HEAD")
+}
@@ -0,0 +1,333 @@
+package gitdir
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// GoGitReader implements the read-only operations in process by go-git,
+// to be combined with a Writer (see Split)
+type GoGitReader struct {
+	repo *git.Repository
+}
+
+var _ Reader = (*GoGitReader)(nil)
+
+// NewGoGitReader opens the repository of the working tree (or of its subdirectory)
+func NewGoGitReader(dir string) (*GoGitReader, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, fmt.Errorf("opening repository %s: %w", dir, err)
+	}
+	return &GoGitReader{repo: repo}, nil
+}
+
+func (g *GoGitReader) CurrentBranch(ctx context.Context) (string, error) {
+	head, err := g.repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", err
+	}
+	if head.Type() != plumbing.SymbolicReference {
+		return "", nil
+	}
+	return head.Target().Short(), nil
+}
+
+func (g *GoGitReader) Status(ctx context.Context) (string, error) {
+	wt, err := g.repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	st, err := wt.Status()
+	if err != nil {
+		return "", err
+	}
+	if st.IsClean() {
+		return "", nil
+	}
+	return strings.TrimSpace(st.String()), nil
+}
+
+// RevParse - the revision is peeled to the commit (rev^{commit}) as by ExecBackend,
+// other ^{type} suffixes are not supported
+func (g *GoGitReader) RevParse(ctx context.Context, rev string) (string, error) {
+	rev = strings.TrimSuffix(rev, "^{commit}")
+	if strings.Contains(rev, "^{") {
+		return "", fmt.Errorf("unsupported revision %s", rev)
+	}
+	hash, err := g.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", err
+	}
+	commit, err := g.peel(*hash)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", rev, err)
+	}
+	return commit.String(), nil
+}
+
+// peel follows the annotated tags to the commit
+func (g *GoGitReader) peel(hash plumbing.Hash) (plumbing.Hash, error) {
+	for {
+		obj, err := g.repo.Storer.EncodedObject(plumbing.AnyObject, hash)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		switch obj.Type() {
+		case plumbing.CommitObject:
+			return hash, nil
+		case plumbing.TagObject:
+			tag, err := object.DecodeTag(g.repo.Storer, obj)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+			hash = tag.Target
+		default:
+			return plumbing.ZeroHash, fmt.Errorf("%s is a %s, not a commit", hash, obj.Type())
+		}
+	}
+}
+
+// ObjectType - abbreviated SHA is resolved to commits only
+func (g *GoGitReader) ObjectType(ctx context.Context, sha string) (string, error) {
+	if !plumbing.IsHash(sha) {
+		if _, err := g.repo.ResolveRevision(plumbing.Revision(sha)); err != nil {
+			return "", err
+		}
+		return plumbing.CommitObject.String(), nil
+	}
+	obj, err := g.repo.Storer.EncodedObject(plumbing.AnyObject, plumbing.NewHash(sha))
+	if err != nil {
+		return "", err
+	}
+	return obj.Type().String(), nil
+}
+
+func (g *GoGitReader) Remotes(ctx context.Context) ([]string, error) {
+	remotes, err := g.repo.Remotes()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(remotes))
+	for i, r := range remotes {
+		names[i] = r.Config().Name
+	}
+	return names, nil
+}
+
+func (g *GoGitReader) RemoteURL(ctx context.Context, remote string) (string, error) {
+	r, err := g.repo.Remote(remote)
+	if err != nil {
+		return "", err
+	}
+	if urls := r.Config().URLs; len(urls) > 0 {
+		return urls[0], nil
+	}
+	return "", fmt.Errorf("remote %s has no url", remote)
+}
+
+// LsRemote - the patterns are matched as by git ls-remote: the glob "*/pattern" against "/ref",
+// with * matching slashes too
+func (g *GoGitReader) LsRemote(ctx context.Context, url string, patterns ...string) ([]RemoteRef, error) {
+	var globs []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := globRegexp("*/" + p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", p, err)
+		}
+		globs = append(globs, re)
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: "origin", URLs: []string{url}})
+	list, err := remote.ListContext(ctx, &git.ListOptions{PeelingOption: git.AppendPeeled})
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", url, err)
+	}
+
+	hashes := map[plumbing.ReferenceName]plumbing.Hash{}
+	for _, ref := range list {
+		if ref.Type() == plumbing.HashReference {
+			hashes[ref.Name()] = ref.Hash()
+		}
+	}
+
+	var refs []RemoteRef
+	for _, ref := range list {
+		name := ref.Name().String()
+		hash := ref.Hash()
+		if ref.Type() == plumbing.SymbolicReference {
+			// HEAD is listed with the SHA of the branch
+			var ok bool
+			if hash, ok = hashes[ref.Target()]; !ok {
+				continue
+			}
+		}
+		if matchesAny("/"+name, globs) {
+			refs = append(refs, RemoteRef{Name: name, SHA: hash.String()})
+		}
+	}
+	// sorted as by git, HEAD first
+	slices.SortFunc(refs, func(a, b RemoteRef) int {
+		switch {
+		case a.Name == b.Name:
+			return 0
+		case a.Name == "HEAD":
+			return -1
+		case b.Name == "HEAD":
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return refs, nil
+}
+
+func matchesAny(name string, globs []*regexp.Regexp) bool {
+	if len(globs) == 0 {
+		return true
+	}
+	for _, re := range globs {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// globRegexp converts the git glob to the regexp, * and ? match slashes too (wildmatch without WM_PATHNAME)
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case '[':
+			// the closing bracket right after [, [! or [^ belongs to the class
+			j := i + 1
+			if j < len(glob) && (glob[j] == '!' || glob[j] == '^') {
+				j++
+			}
+			if j < len(glob) && glob[j] == ']' {
+				j++
+			}
+			for j < len(glob) && glob[j] != ']' {
+				j++
+			}
+			if j == len(glob) {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : j]
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i = j
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+func (g *GoGitReader) UnmergedPaths(ctx context.Context) ([]string, error) {
+	idx, err := g.repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range idx.Entries {
+		// the entries are sorted by the path and the stage, the merged ones are at stage 0
+		// (index.Merged is 1, the same as index.AncestorMode)
+		if e.Stage != 0 && (len(paths) == 0 || paths[len(paths)-1] != e.Name) {
+			paths = append(paths, e.Name)
+		}
+	}
+	return paths, nil
+}
+
+func (g *GoGitReader) MergeInProgress(ctx context.Context) (bool, error) {
+	_, err := g.repo.Reference("MERGE_HEAD", false)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (g *GoGitReader) IsAncestor(ctx context.Context, commit, rev string) (bool, error) {
+	var commits [2]*object.Commit
+	for i, r := range []string{commit, rev} {
+		sha, err := g.RevParse(ctx, r)
+		if err != nil {
+			return false, err
+		}
+		if commits[i], err = g.repo.CommitObject(plumbing.NewHash(sha)); err != nil {
+			return false, err
+		}
+	}
+	return commits[0].IsAncestor(commits[1])
+}
+
+// Note - the notes tree may fan out the names (ab/cdef...) as git notes does
+func (g *GoGitReader) Note(ctx context.Context, notesRef, rev string) (string, error) {
+	hash, err := g.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", err
+	}
+	ref, err := g.repo.Reference(plumbing.ReferenceName(notesRef), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	commit, err := g.repo.CommitObject(ref.Hash())
+	if err != nil {
+		return "", err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", err
+	}
+
+	name := hash.String()
+	for {
+		if f, err := tree.File(name); err == nil {
+			return f.Contents()
+		}
+		if len(name) <= 2 {
+			return "", nil
+		}
+		if tree, err = tree.Tree(name[:2]); err != nil {
+			return "", nil
+		}
+		name = name[2:]
+	}
+}
+
+func (g *GoGitReader) GitDir(ctx context.Context) (string, error) {
+	fs, ok := g.repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return "", fmt.Errorf("repository is not on the filesystem")
+	}
+	return fs.Filesystem().Root(), nil
+}
@@ -0,0 +1,140 @@
+package gitdir
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		glob, name string
+		want       bool
+	}{
+		{"*/main", "/refs/heads/main", true},
+		{"*/main", "/refs/heads/xmain", false},
+		{"*/heads/*", "/refs/heads/feature/x", true},
+		{"*/refs/heads/main", "/refs/heads/main", true},
+		{"*/v1.?", "/refs/tags/v1.2", true},
+		{"*/v1.?", "/refs/tags/v1.23", false},
+		{"*/v[0-9]*", "/refs/tags/v2.0", true},
+		{"*/v[!0-9]*", "/refs/tags/v2.0", false},
+		{"*/[]]", "/refs/heads/]", true},
+		{"*/a.b", "/refs/heads/axb", false},
+		{`*/a\*`, "/refs/heads/a*", true},
+		{`*/a\*`, "/refs/heads/ab", false},
+		{"*/[ab", "/refs/heads/[ab", true},
+	}
+	for _, tt := range tests {
+		re, err := globRegexp(tt.glob)
+		if err != nil {
+			t.Errorf("globRegexp(%q): %v", tt.glob, err)
+			continue
+		}
+		if got := re.MatchString(tt.name); got != tt.want {
+			t.Errorf("%q matches %q = %v, want %v", tt.glob, tt.name, got, tt.want)
+		}
+	}
+}
+
+// testRepo creates the repository with the branches main and feature conflicting on f.txt,
+// the annotated tag v1.0.0 and the merge of feature stopped on the conflict
+func testRepo(t *testing.T) *Dir {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	wd := &Dir{Dir: t.TempDir(), Env: []string{
+		"HOME=" + t.TempDir(),
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	}}
+	ctx := context.Background()
+	script := [][]string{
+		{"init", "-q", "-b", "main"},
+		{"commit", "-q", "--allow-empty", "-m", "base"},
+		{"tag", "-a", "-m", "release", "v1.0.0"},
+		{"checkout", "-q", "-b", "feature"},
+		{"commit", "-q", "--allow-empty", "-m", "feature"},
+		{"checkout", "-q", "main"},
+		{"notes", "--ref", "refs/notes/test", "add", "-m", "note of main", "main"},
+	}
+	for _, args := range script {
+		if _, err := wd.Run(ctx, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, branch := range []string{"feature", "main"} {
+		if _, err := wd.Run(ctx, "checkout", "-q", branch); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(wd.Dir, "f.txt"), []byte(branch+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", "f.txt"}, {"commit", "-q", "-m", branch}} {
+			if _, err := wd.Run(ctx, args...); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if _, err := wd.Run(ctx, "merge", "-q", "feature"); ExitCode(err) != 1 {
+		t.Fatalf("merge of feature did not conflict: %v", err)
+	}
+	return wd
+}
+
+func TestGoGitReaderMatchesExec(t *testing.T) {
+	wd := testRepo(t)
+	ctx := context.Background()
+	gg, err := NewGoGitReader(wd.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readers := map[string]Reader{"exec": &ExecBackend{Dir: wd}, "gogit": gg}
+
+	type call struct {
+		name string
+		read func(r Reader) (any, error)
+	}
+	calls := []call{
+		{"RevParse main", func(r Reader) (any, error) { return r.RevParse(ctx, "main") }},
+		{"RevParse annotated tag", func(r Reader) (any, error) { return r.RevParse(ctx, "v1.0.0") }},
+		{"RevParse ^{commit}", func(r Reader) (any, error) { return r.RevParse(ctx, "v1.0.0^{commit}") }},
+		{"RevParse MERGE_HEAD", func(r Reader) (any, error) { return r.RevParse(ctx, "MERGE_HEAD") }},
+		{"CurrentBranch", func(r Reader) (any, error) { return r.CurrentBranch(ctx) }},
+		{"UnmergedPaths", func(r Reader) (any, error) { return r.UnmergedPaths(ctx) }},
+		{"MergeInProgress", func(r Reader) (any, error) { return r.MergeInProgress(ctx) }},
+		{"IsAncestor", func(r Reader) (any, error) { return r.IsAncestor(ctx, "v1.0.0", "feature") }},
+		{"IsAncestor self", func(r Reader) (any, error) { return r.IsAncestor(ctx, "main", "main") }},
+		{"not IsAncestor", func(r Reader) (any, error) { return r.IsAncestor(ctx, "main", "feature") }},
+		{"Note", func(r Reader) (any, error) { return r.Note(ctx, "refs/notes/test", "main~1") }},
+		{"no Note", func(r Reader) (any, error) { return r.Note(ctx, "refs/notes/test", "feature") }},
+		{"GitDir", func(r Reader) (any, error) { return r.GitDir(ctx) }},
+	}
+	for _, pattern := range [][]string{nil, {"main"}, {"heads/*"}, {"refs/tags/v1.*"}, {"v1.0.0^{}"}, {"ain"}} {
+		calls = append(calls, call{"LsRemote " + strings.Join(pattern, " "), func(r Reader) (any, error) {
+			return r.LsRemote(ctx, wd.Dir, pattern...)
+		}})
+	}
+
+	for _, c := range calls {
+		t.Run(c.name, func(t *testing.T) {
+			want, err := c.read(readers["exec"])
+			if err != nil {
+				t.Fatalf("exec: %v", err)
+			}
+			got, err := c.read(readers["gogit"])
+			if err != nil {
+				t.Fatalf("gogit: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("gogit = %v, exec = %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package gitdir
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MergeTreeResult is the result of the merge performed by git merge-tree,
+// without touching the working tree or the index
+type MergeTreeResult struct {
+	// the tree of the merge, with conflict markers if there are any conflicts
+	Tree string
+	// conflicting paths, empty for clean merge
+	Conflicts []string
+}
+
+func (r *MergeTreeResult) Clean() bool {
+	return len(r.Conflicts) == 0
+}
+
+// MergeTree merges two commits in memory (git merge-tree --write-tree), requires git 2.38 or newer
+func (wd *Dir) MergeTree(ctx context.Context, ours, theirs string) (*MergeTreeResult, error) {
+	result, err := wd.Git().MergeTree(ctx, ours, theirs)
+	if err != nil {
+		return nil, fmt.Errorf("merge-tree of %s and %s: %w", ours, theirs, err)
+	}
+	return result, nil
+}
+
+// parseMergeTree parses the output of git merge-tree --write-tree --name-only --no-messages:
+// the tree followed by the conflicting paths (listed per stage) if the merge is not clean.
+// git exits with 1 also on unknown revision, the output is empty then.
+func parseMergeTree(out string, conflicts bool) (*MergeTreeResult, error) {
+	lines := strings.Split(out, "\n")
+	if lines[0] == "" {
+		return nil, fmt.Errorf("no tree in the output of merge-tree")
+	}
+	result := &MergeTreeResult{Tree: lines[0]}
+	if conflicts {
+		seen := map[string]bool{}
+		for _, path := range lines[1:] {
+			if path != "" && !seen[path] {
+				seen[path] = true
+				result.Conflicts = append(result.Conflicts, path)
+			}
+		}
+	}
+	return result, nil
+}
+
+// CommitTree creates a commit object of the tree, without updating any ref
+func (wd *Dir) CommitTree(ctx context.Context, tree, message string, parents ...string) (string, error) {
+	out, err := wd.Git().CommitTree(ctx, tree, message, parents...)
+	if err != nil {
+		return "", fmt.Errorf("commit-tree %s: %w", tree, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,93 @@
+package gitdir
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseMergeTree(t *testing.T) {
+	tests := []struct {
+		name      string
+		out       string
+		conflicts bool
+		want      *MergeTreeResult
+		wantErr   bool
+	}{
+		{
+			name: "clean",
+			out:  "4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+			want: &MergeTreeResult{Tree: "4b825dc642cb6eb9a060e54bf8d69288fbee4904"},
+		},
+		{
+			name:      "conflicts listed per stage",
+			out:       "1f2e3d\na.txt\na.txt\na.txt\ndir/b.txt\ndir/b.txt\n",
+			conflicts: true,
+			want:      &MergeTreeResult{Tree: "1f2e3d", Conflicts: []string{"a.txt", "dir/b.txt"}},
+		},
+		{
+			name:      "conflict without paths",
+			out:       "1f2e3d\n",
+			conflicts: true,
+			want:      &MergeTreeResult{Tree: "1f2e3d"},
+		},
+		{
+			name:      "unknown revision",
+			out:       "",
+			conflicts: true,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMergeTree(tt.out, tt.conflicts)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseMergeTree = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMergeTree = %+v, want %+v", got, tt.want)
+			}
+			if got.Clean() != (len(tt.want.Conflicts) == 0) {
+				t.Errorf("Clean = %v", got.Clean())
+			}
+		})
+	}
+}
+
+func TestMergeTree(t *testing.T) {
+	wd := testRepo(t)
+	ctx := context.Background()
+
+	result, err := wd.MergeTree(ctx, "main", "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Conflicts, []string{"f.txt"}) {
+		t.Errorf("conflicts = %v, want [f.txt]", result.Conflicts)
+	}
+
+	result, err = wd.MergeTree(ctx, "main", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Clean() {
+		t.Fatalf("conflicts = %v", result.Conflicts)
+	}
+	commit, err := wd.CommitTree(ctx, result.Tree, "merge", "main", "v1.0.0^{commit}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := wd.IsAncestor(ctx, "main", commit); err != nil || !ok {
+		t.Errorf("main is not in history of the merge commit: %v", err)
+	}
+
+	if _, err := wd.MergeTree(ctx, "main", "unknown"); err == nil {
+		t.Error("merge of unknown revision succeeded")
+	}
+}
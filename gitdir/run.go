@@ -0,0 +1,124 @@
+package gitdir
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Result is the record of a finished git invocation
+type Result struct {
+	Dir string
+	// argv, starting with "git"
+	Args   []string
+	Stdout []byte
+	Stderr []byte
+	// -1 if git did not exit (not started, killed by a signal)
+	ExitCode int
+	Duration time.Duration
+}
+
+// String returns the trimmed stdout
+func (r *Result) String() string {
+	return strings.TrimSpace(string(r.Stdout))
+}
+
+// GitError is returned for the failed git invocation
+type GitError struct {
+	*Result
+	Err error
+}
+
+func (e *GitError) Error() string {
+	msg := fmt.Sprintf("%s failed (exit code %d after %s)",
+		strings.Join(e.Args, " "), e.ExitCode, e.Duration.Round(time.Millisecond))
+	if stderr := strings.TrimSpace(string(e.Stderr)); stderr != "" {
+		msg += ": " + stderr
+	} else if e.ExitCode < 0 {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *GitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the failed git from the error (possibly wrapped), -1 if there is none
+func ExitCode(err error) int {
+	var gitErr *GitError
+	if errors.As(err, &gitErr) {
+		return gitErr.ExitCode
+	}
+	return -1
+}
+
+// Hook observes every git invocation made by Run, err is nil or *GitError
+type Hook func(ctx context.Context, r *Result, err error)
+
+// SlogHook logs every git invocation with the logger at the level, failures include stderr
+func SlogHook(logger *slog.Logger, level slog.Level) Hook {
+	return func(ctx context.Context, r *Result, err error) {
+		attrs := []slog.Attr{
+			slog.String("dir", r.Dir),
+			slog.Int("exit_code", r.ExitCode),
+			slog.Duration("duration", r.Duration),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("stderr", strings.TrimSpace(string(r.Stderr))))
+		}
+		logger.LogAttrs(ctx, level, strings.Join(r.Args, " "), attrs...)
+	}
+}
+
+// Run runs git with the args in the working tree, capturing its output
+func (wd *Dir) Run(ctx context.Context, args ...string) (*Result, error) {
+	return wd.RunInput(ctx, nil, args...)
+}
+
+// RunInput is Run with the standard input of git
+func (wd *Dir) RunInput(ctx context.Context, stdin io.Reader, args ...string) (*Result, error) {
+	cmd := wd.CommandContext(ctx, "git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	started := time.Now()
+	err := cmd.Run()
+	r := &Result{
+		Dir:      wd.Dir,
+		Args:     cmd.Args,
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		ExitCode: -1,
+		Duration: time.Since(started),
+	}
+	if cmd.ProcessState != nil {
+		r.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = fmt.Errorf("%w: %w", ctxErr, err)
+		}
+		err = &GitError{Result: r, Err: err}
+	}
+	if wd.Hook != nil {
+		wd.Hook(ctx, r, err)
+	}
+	return r, err
+}
+
+// Output runs git and returns its trimmed stdout
+func (wd *Dir) Output(ctx context.Context, args ...string) (string, error) {
+	r, err := wd.Run(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	return r.String(), nil
+}
@@ -5,8 +5,17 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"sync/atomic"
 )
 
+var shellRunning atomic.Bool
+
+// ShellRunning returns true while the interactive shell of RunBashWithPrompt runs,
+// the interrupts (Ctrl-C) belong to the shell then and should be ignored by the caller
+func ShellRunning() bool {
+	return shellRunning.Load()
+}
+
 func (wd *Dir) RunBashWithPrompt(prompt string) error {
 	// escaping apostrophe
 	prompt = strings.ReplaceAll(prompt, "'", "'\\''")
@@ -35,5 +44,7 @@ func (wd *Dir) RunBashWithPrompt(prompt string) error {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
+	shellRunning.Store(true)
+	defer shellRunning.Store(false)
 	return cmd.Run()
 }
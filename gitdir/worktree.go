@@ -0,0 +1,98 @@
+package gitdir
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultWorktreePath returns the location of the worktree of the branch in the user cache directory,
+// it is distinct for every repository
+func (wd *Dir) DefaultWorktreePath(branch string) (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locating cache dir: %w", err)
+	}
+	sum := sha256.Sum256([]byte(wd.Dir))
+	repo := filepath.Base(wd.Dir) + "-" + hex.EncodeToString(sum[:])[:12]
+	return filepath.Join(cache, "mergeexp", "worktrees", repo, branch), nil
+}
+
+// Worktree returns the linked working tree at path checked out on the branch started from target,
+// the worktree is created or reused if it exists already. As with StartExperimentalBranch
+// the local changes in the worktree are discarded. The working tree of wd is not touched,
+// yet the branch must not be checked out there.
+func (wd *Dir) Worktree(ctx context.Context, path, branch, target string) (*Dir, error) {
+	wt, err := wd.OpenWorktree(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if wt != nil {
+		if err := wt.StartExperimentalBranch(ctx, branch, target); err != nil {
+			return nil, err
+		}
+		return wt, nil
+	}
+
+	// forgetting the worktrees removed without git
+	if _, err := wd.Run(ctx, "worktree", "prune"); err != nil {
+		return nil, fmt.Errorf("pruning worktrees: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating worktree dir: %w", err)
+	}
+	// -B creates or resets the branch
+	if _, err := wd.Run(ctx, "worktree", "add", "-B", branch, path, target); err != nil {
+		return nil, fmt.Errorf("creating worktree %s: %w", path, err)
+	}
+	return wd.worktreeDir(path)
+}
+
+// OpenWorktree returns the existing linked working tree at path, nil if there is none
+func (wd *Dir) OpenWorktree(ctx context.Context, path string) (*Dir, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot make '%s' absolute", path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+
+	out, err := wd.Output(ctx, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("listing worktrees: %w", err)
+	}
+	for line := range strings.Lines(out) {
+		if strings.TrimSpace(line) == "worktree "+path {
+			return wd.worktreeDir(path)
+		}
+	}
+	return nil, nil
+}
+
+// RemoveWorktree removes the linked working tree including its local changes, the branch is kept
+func (wd *Dir) RemoveWorktree(ctx context.Context, path string) error {
+	if _, err := wd.Run(ctx, "worktree", "remove", "--force", path); err != nil {
+		return fmt.Errorf("removing worktree %s: %w", path, err)
+	}
+	return nil
+}
+
+// worktreeDir returns the Dir of the worktree sharing the environment and the hook of wd,
+// the backend is the default one
+func (wd *Dir) worktreeDir(path string) (*Dir, error) {
+	wt, err := New(path)
+	if err != nil {
+		return nil, err
+	}
+	wt.Env = wd.Env
+	wt.Hook = wd.Hook
+	return wt, nil
+}
@@ -0,0 +1,110 @@
+package gitea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// PageLimit is the number of items requested per page, Gitea caps it by MAX_RESPONSE_ITEMS (50 by default)
+const PageLimit = 50
+
+type Client struct {
+	restClient *resty.Client
+}
+
+// NewClient expects resty client with base URL set to the API root (https://gitea.example.com/api/v1)
+// and the authorization header ("token <token>") set
+func NewClient(rc *resty.Client) *Client {
+	return &Client{restClient: rc}
+}
+
+func (c *Client) Req(ctx context.Context) *resty.Request {
+	return c.restClient.R().SetContext(ctx)
+}
+
+var (
+	RepositoryNotFound  = errors.New("gitea repository not found")
+	PullRequestNotFound = errors.New("gitea pull request not found")
+	CommentNotFound     = errors.New("gitea comment not found")
+)
+
+// responseError returns the error of the failed response,
+// 404 is the missing entity of the endpoint (notFound)
+func responseError(resp *resty.Response, notFound error, action string) error {
+	if resp.StatusCode() == http.StatusNotFound {
+		return notFound
+	}
+	return fmt.Errorf("%s failed with %s status", action, resp.Status())
+}
+
+// PullRequests returns open, non draft pull requests of the repository (owner/name)
+// against any of the base branches (all if none given) having all the labels
+func (c *Client) PullRequests(ctx context.Context, fullname string, bases []string, labels ...string) ([]PullRequest, error) {
+	var prs []PullRequest
+
+	// paging, the server may cap the limit, the longest page seen is the limit applied
+	fetched, limit := 0, 0
+	for page := 1; ; page++ {
+		var prsPage []PullRequest
+
+		resp, err := c.Req(ctx).
+			SetQueryParam("state", "open").
+			SetQueryParam("page", strconv.Itoa(page)).
+			SetQueryParam("limit", strconv.Itoa(PageLimit)).
+			SetResult(&prsPage).
+			Get(fmt.Sprintf("repos/%s/pulls", fullname))
+		if err != nil {
+			return nil, fmt.Errorf("gitea failed: %w", err)
+		}
+		if !resp.IsSuccess() {
+			return nil, responseError(resp, RepositoryNotFound, "fetch for Gitea pull requests")
+		}
+
+		for _, pr := range prsPage {
+			if pr.IsDraft() {
+				continue
+			}
+			if len(bases) > 0 && !slices.Contains(bases, pr.Base.Ref) {
+				continue
+			}
+			if !pr.HasLabels(labels...) {
+				continue
+			}
+			prs = append(prs, pr)
+		}
+
+		fetched += len(prsPage)
+		limit = max(limit, len(prsPage))
+		// the total count is preferred when present
+		total, err := strconv.Atoi(resp.Header().Get("X-Total-Count"))
+		if len(prsPage) == 0 || (err == nil && fetched >= total) || (err != nil && len(prsPage) < limit) {
+			break
+		}
+	}
+
+	return prs, nil
+}
+
+// RepoSSHUrl returns SSH clone URL of the repository (owner/name)
+func (c *Client) RepoSSHUrl(ctx context.Context, fullname string) (string, error) {
+	var repo Repository
+	res, err := c.Req(ctx).
+		SetResult(&repo).
+		Get(fmt.Sprintf("repos/%s", fullname))
+	if err != nil {
+		return "", fmt.Errorf("gitea call failed: %w", err)
+	}
+	if !res.IsSuccess() {
+		if res.StatusCode() == http.StatusNotFound {
+			return "", RepositoryNotFound
+		}
+		return "", fmt.Errorf("gitea call returned: %d", res.StatusCode())
+	}
+	return repo.SSHURL, nil
+}
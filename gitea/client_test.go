@@ -0,0 +1,345 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/wayan/mergeexp/gitdir/gitdirtest"
+	"github.com/wayan/mergeexp/merger"
+	"github.com/wayan/mergeexp/provider"
+)
+
+// testClient returns the client of the server with the handler mounted at /api/v1
+func testClient(t *testing.T, handler http.Handler) *Client {
+	srv := httptest.NewServer(http.StripPrefix("/api/v1", handler))
+	t.Cleanup(srv.Close)
+	return NewClient(resty.New().SetBaseURL(srv.URL + "/api/v1"))
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Error(err)
+	}
+}
+
+// writeCreated answers 201 as Gitea does for the created entities
+func writeCreated(t *testing.T, w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Error(err)
+	}
+}
+
+// pullsHandler serves the pull requests in pages of at most maxLimit items (MAX_RESPONSE_ITEMS),
+// with X-Total-Count header if total is set, the requested pages are recorded
+func pullsHandler(t *testing.T, prs []PullRequest, maxLimit int, total bool, pages *[]int) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("state") != "open" {
+			t.Errorf("query %s", r.URL.RawQuery)
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		limit = min(limit, maxLimit)
+		*pages = append(*pages, page)
+
+		from, to := min((page-1)*limit, len(prs)), min(page*limit, len(prs))
+		if total {
+			w.Header().Set("X-Total-Count", strconv.Itoa(len(prs)))
+		}
+		writeJSON(t, w, prs[from:to])
+	})
+	return mux
+}
+
+func numbers(prs []PullRequest) []int {
+	var ns []int
+	for _, pr := range prs {
+		ns = append(ns, pr.Number)
+	}
+	return ns
+}
+
+func TestPullRequestsPaging(t *testing.T) {
+	var prs []PullRequest
+	for n := 1; n <= 7; n++ {
+		prs = append(prs, PullRequest{Number: n})
+	}
+	tests := []struct {
+		name     string
+		count    int
+		maxLimit int
+		total    bool
+		pages    []int
+	}{
+		{"single page", 7, PageLimit, true, []int{1}},
+		// without the total the first page cannot be told from a page of capped limit
+		{"single page without total", 7, PageLimit, false, []int{1, 2}},
+		{"capped limit", 7, 3, true, []int{1, 2, 3}},
+		{"capped limit without total", 7, 3, false, []int{1, 2, 3}},
+		// the full last page cannot be told from the capped one without the total
+		{"full last page", 6, 3, true, []int{1, 2}},
+		{"full last page without total", 6, 3, false, []int{1, 2, 3}},
+		{"empty", 0, 3, true, []int{1}},
+		{"empty without total", 0, 3, false, []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pages []int
+			c := testClient(t, pullsHandler(t, prs[:tt.count], tt.maxLimit, tt.total, &pages))
+
+			got, err := c.PullRequests(context.Background(), "o/r", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.count {
+				t.Errorf("%d pull requests, want %d", len(got), tt.count)
+			}
+			if !reflect.DeepEqual(pages, tt.pages) {
+				t.Errorf("pages %v fetched, want %v", pages, tt.pages)
+			}
+		})
+	}
+}
+
+func TestPullRequestsFilter(t *testing.T) {
+	labels := func(names ...string) []Label {
+		var ls []Label
+		for _, n := range names {
+			ls = append(ls, Label{Name: n})
+		}
+		return ls
+	}
+	prs := []PullRequest{
+		{Number: 1, Base: Ref{Ref: "main"}, Labels: labels("exp", "other")},
+		{Number: 2, Base: Ref{Ref: "main"}, Labels: labels("exp"), Draft: true},
+		{Number: 3, Base: Ref{Ref: "main"}, Labels: labels("exp"), Title: "wip: not yet"},
+		{Number: 4, Base: Ref{Ref: "release"}, Labels: labels("exp")},
+		{Number: 5, Base: Ref{Ref: "main"}},
+	}
+	var pages []int
+	c := testClient(t, pullsHandler(t, prs, PageLimit, true, &pages))
+
+	tests := []struct {
+		bases  []string
+		labels []string
+		want   []int
+	}{
+		{nil, nil, []int{1, 4, 5}},
+		{nil, []string{"exp"}, []int{1, 4}},
+		{[]string{"main"}, []string{"exp", "other"}, []int{1}},
+		{[]string{"release", "main"}, nil, []int{1, 4, 5}},
+		{[]string{"release"}, nil, []int{4}},
+	}
+	for _, tt := range tests {
+		got, err := c.PullRequests(context.Background(), "o/r", tt.bases, tt.labels...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ns := numbers(got); !reflect.DeepEqual(ns, tt.want) {
+			t.Errorf("PullRequests(%v, %v) = %v, want %v", tt.bases, tt.labels, ns, tt.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/broken/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Server Error"}`, http.StatusInternalServerError)
+	})
+	// everything else is not found
+	c := testClient(t, mux)
+	ctx := context.Background()
+
+	if _, err := c.PullRequests(ctx, "o/gone", nil); !errors.Is(err, RepositoryNotFound) {
+		t.Errorf("PullRequests of missing repository: %v", err)
+	}
+	if _, err := c.RepoSSHUrl(ctx, "o/gone"); !errors.Is(err, RepositoryNotFound) {
+		t.Errorf("RepoSSHUrl of missing repository: %v", err)
+	}
+	if _, err := c.Comments(ctx, "o/r", 7); !errors.Is(err, PullRequestNotFound) {
+		t.Errorf("Comments of missing pull request: %v", err)
+	}
+	if _, err := c.UpdateComment(ctx, "o/r", 7, "body"); !errors.Is(err, CommentNotFound) {
+		t.Errorf("UpdateComment of missing comment: %v", err)
+	}
+	_, err := c.PullRequests(ctx, "o/broken", nil)
+	if err == nil || err.Error() != "fetch for Gitea pull requests failed with 500 Internal Server Error status" {
+		t.Errorf("PullRequests server error: %v", err)
+	}
+}
+
+// fakeGitea serves pull requests, their comments and statuses of the repository o/r
+type fakeGitea struct {
+	t        *testing.T
+	prs      []PullRequest
+	comments map[int][]Comment
+	statuses map[string][]Status
+	lastID   int
+}
+
+func newFakeGitea(t *testing.T, prs ...PullRequest) *fakeGitea {
+	return &fakeGitea{t: t, prs: prs, comments: map[int][]Comment{}, statuses: map[string][]Status{}}
+}
+
+func (f *fakeGitea) comment(number int, body string) {
+	f.lastID++
+	f.comments[number] = append(f.comments[number], Comment{ID: f.lastID, Body: body})
+}
+
+func (f *fakeGitea) handler() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			writeJSON(f.t, w, []PullRequest{})
+			return
+		}
+		writeJSON(f.t, w, f.prs)
+	})
+	mux.HandleFunc("GET /repos/o/r/issues/{number}/comments", func(w http.ResponseWriter, r *http.Request) {
+		number, _ := strconv.Atoi(r.PathValue("number"))
+		writeJSON(f.t, w, append([]Comment{}, f.comments[number]...))
+	})
+	mux.HandleFunc("POST /repos/o/r/issues/{number}/comments", func(w http.ResponseWriter, r *http.Request) {
+		number, _ := strconv.Atoi(r.PathValue("number"))
+		var c Comment
+		json.NewDecoder(r.Body).Decode(&c)
+		f.comment(number, c.Body)
+		writeCreated(f.t, w, f.comments[number][len(f.comments[number])-1])
+	})
+	mux.HandleFunc("PATCH /repos/o/r/issues/comments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var c Comment
+		json.NewDecoder(r.Body).Decode(&c)
+		for _, comments := range f.comments {
+			for i := range comments {
+				if r.PathValue("id") == strconv.Itoa(comments[i].ID) {
+					comments[i].Body = c.Body
+					writeJSON(f.t, w, comments[i])
+					return
+				}
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("POST /repos/o/r/statuses/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		var s Status
+		json.NewDecoder(r.Body).Decode(&s)
+		f.statuses[r.PathValue("sha")] = append(f.statuses[r.PathValue("sha")], s)
+		writeCreated(f.t, w, s)
+	})
+	return mux
+}
+
+func TestChangeRequestsByDirective(t *testing.T) {
+	f := newFakeGitea(t,
+		PullRequest{Number: 1, Title: "labeled", Labels: []Label{{Name: "exp"}}, Head: Ref{Sha: "h1"}},
+		PullRequest{Number: 2, Title: "deployed", Head: Ref{Sha: "h2"}},
+		PullRequest{Number: 3, Title: "withdrawn", Head: Ref{Sha: "h3"}},
+		PullRequest{Number: 4, Title: "untouched", Head: Ref{Sha: "h4"}},
+	)
+	f.comment(2, "LGTM")
+	f.comment(2, "deployment: exp@abcdef1 priority=2 after #1")
+	f.comment(3, "deployment: exp")
+	f.comment(3, "deployment: no-exp")
+	p := NewProvider(testClient(t, f.handler()), "o/r")
+
+	refs, err := p.ChangeRequests(context.Background(), provider.Filter{Labels: []string{"exp"}, Tags: []string{"exp"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, ref := range refs {
+		names = append(names, ref.Name())
+	}
+	if want := []string{"PR 1: labeled", "PR 2: deployed"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("selected %v, want %v", names, want)
+	}
+	if labeled := refs[0].(mergeRef); labeled.Sha() != "h1" || labeled.Priority() != 0 {
+		t.Errorf("labeled pull request: sha %s, priority %d", labeled.Sha(), labeled.Priority())
+	}
+	deployed := refs[1].(mergeRef)
+	if deployed.Sha() != "abcdef1" || deployed.Priority() != 2 || !reflect.DeepEqual(deployed.DependsOn(), []int{1}) {
+		t.Errorf("directive not applied: sha %s, priority %d, after %v", deployed.Sha(), deployed.Priority(), deployed.DependsOn())
+	}
+}
+
+func TestFetch(t *testing.T) {
+	origin := gitdirtest.Repo(t)
+	listed := gitdirtest.Commit(t, origin, "a.txt", "1")
+	gitdirtest.Git(t, origin, "update-ref", "refs/pull/1/head", listed)
+	moved := gitdirtest.Commit(t, origin, "a.txt", "2")
+	gitdirtest.Git(t, origin, "update-ref", "refs/pull/2/head", moved)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/o/r", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, Repository{FullName: "o/r", SSHURL: origin.Dir})
+	})
+	p := NewProvider(testClient(t, mux), "o/r")
+	prs := []PullRequest{{Number: 1, Head: Ref{Sha: listed}}, {Number: 2, Head: Ref{Sha: listed}}}
+	refs := []merger.MergeRef{prs[0].MergeRef(), prs[1].MergeRef()}
+
+	dir := gitdirtest.Repo(t)
+	if err := p.Fetch(context.Background(), dir, refs); err != nil {
+		t.Fatal(err)
+	}
+	if refs[0].Sha() != listed || refs[1].Sha() != moved {
+		t.Errorf("fetched heads %s, %s, want %s and the moved %s", refs[0].Sha(), refs[1].Sha(), listed, moved)
+	}
+}
+
+func TestFeedback(t *testing.T) {
+	f := newFakeGitea(t, PullRequest{Number: 7, Title: "feature", Head: Ref{Sha: "head"}, HTMLURL: "https://gitea.example.com/o/r/pulls/7"})
+	f.comment(7, "unrelated")
+	p := NewProvider(testClient(t, f.handler()), "o/r")
+	ctx := context.Background()
+
+	refs, err := p.ChangeRequests(ctx, provider.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := provider.Feedback{Branch: "exp", Commit: "c1", Result: merger.RefReport{SHA: "head", Outcome: merger.OutcomeMerged}}
+	if err := p.Feedback(ctx, refs[0], merged); err != nil {
+		t.Fatal(err)
+	}
+	skipped := provider.Feedback{Branch: "exp", Commit: "c2", Result: merger.RefReport{
+		SHA: "head", Outcome: merger.OutcomeSkipped, ConflictPaths: []string{"a.txt", "b.txt"},
+	}}
+	for range 2 {
+		if err := p.Feedback(ctx, refs[0], skipped); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the single comment of the branch is updated
+	comments := f.comments[7]
+	if len(comments) != 2 || comments[1].Body != provider.FeedbackText(provider.CommentMarker("exp"), skipped) {
+		t.Errorf("comments = %+v", comments)
+	}
+	url := "https://gitea.example.com/o/r/pulls/7"
+	want := []Status{
+		{State: StatusSuccess, TargetURL: url, Description: "in experimental build at c1", Context: "mergeexp/exp"},
+		{State: StatusFailure, TargetURL: url, Description: "conflict: a.txt, b.txt", Context: "mergeexp/exp"},
+		{State: StatusFailure, TargetURL: url, Description: "conflict: a.txt, b.txt", Context: "mergeexp/exp"},
+	}
+	if !reflect.DeepEqual(f.statuses["head"], want) {
+		t.Errorf("statuses = %+v, want %+v", f.statuses["head"], want)
+	}
+
+	if err := p.Feedback(ctx, foreignRef{}, merged); !errors.Is(err, provider.ErrForeignRef) {
+		t.Errorf("Feedback of foreign ref: %v", err)
+	}
+}
+
+type foreignRef struct{}
+
+func (foreignRef) Name() string { return "foreign" }
+func (foreignRef) Sha() string  { return "foreign" }
@@ -0,0 +1,130 @@
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/wayan/mergeexp/gitdir"
+	"github.com/wayan/mergeexp/merger"
+	"github.com/wayan/mergeexp/provider"
+	"github.com/wayan/mergeexp/selection"
+)
+
+// Provider implements provider.Provider for pull requests of the Gitea repository
+type Provider struct {
+	Client *Client
+	// owner/name
+	Repository string
+}
+
+var _ provider.Provider = (*Provider)(nil)
+
+func NewProvider(client *Client, repository string) *Provider {
+	return &Provider{Client: client, Repository: repository}
+}
+
+// ChangeRequests returns pull requests selected by selection.Policy: labels OR deployment directives in comments
+func (p *Provider) ChangeRequests(ctx context.Context, f provider.Filter) ([]merger.MergeRef, error) {
+	policy := selection.Policy{Labels: f.Labels, Tags: f.Tags}
+	// without tags the labels alone are filtered by the client
+	var labels []string
+	if len(f.Tags) == 0 {
+		labels = f.Labels
+	}
+	prs, err := p.Client.PullRequests(ctx, p.Repository, f.TargetBranches, labels...)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []merger.MergeRef
+	for i := range prs {
+		var prLabels []string
+		for _, l := range prs[i].Labels {
+			prLabels = append(prLabels, l.Name)
+		}
+		ok, directive, err := policy.Select(prLabels, func() ([]string, error) {
+			return p.commentTexts(ctx, prs[i].Number)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			prs[i].Directive = directive
+			refs = append(refs, prs[i].MergeRef())
+		}
+	}
+	return refs, nil
+}
+
+// commentTexts returns bodies of the comments of the pull request, from the oldest
+func (p *Provider) commentTexts(ctx context.Context, number int) ([]string, error) {
+	comments, err := p.Client.Comments(ctx, p.Repository, number)
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		texts = append(texts, comment.Body)
+	}
+	return texts, nil
+}
+
+func (p *Provider) CloneURL(ctx context.Context, ref merger.MergeRef) (string, error) {
+	pr, ok := ref.(mergeRef)
+	if !ok {
+		return "", provider.ErrForeignRef
+	}
+	if repo := pr.Head.Repo; repo != nil && repo.SSHURL != "" {
+		return repo.SSHURL, nil
+	}
+	return "", fmt.Errorf("source repository of %s is gone", pr.Name())
+}
+
+// LocalRefPrefix is the prefix of the local refs the pull request heads are fetched into
+const LocalRefPrefix = "refs/mergeexp/pull/"
+
+// Fetch fetches refs/pull/<number>/head from the repository, which works for forks too.
+// Pull requests whose head moved since listing are merged at the fetched head, see provider.FetchHeads.
+func (p *Provider) Fetch(ctx context.Context, dir *gitdir.Dir, refs []merger.MergeRef) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	url, err := p.Client.RepoSSHUrl(ctx, p.Repository)
+	if err != nil {
+		return err
+	}
+
+	var prs []*PullRequest
+	var heads []provider.Head
+	for _, ref := range refs {
+		pr, ok := ref.(mergeRef)
+		if !ok {
+			return provider.ErrForeignRef
+		}
+		prs = append(prs, pr.PullRequest)
+		heads = append(heads, provider.Head{
+			Name:   pr.Name(),
+			Remote: fmt.Sprintf("refs/pull/%d/head", pr.Number),
+			Local:  LocalRefPrefix + strconv.Itoa(pr.Number),
+			Sha:    pr.Head.Sha,
+		})
+	}
+	if err := provider.FetchHeads(ctx, dir, url, heads); err != nil {
+		return err
+	}
+	for i, pr := range prs {
+		pr.Head.Sha = heads[i].Sha
+		provider.CheckPinned(ctx, dir, pr.MergeRef().Name(), pr.Directive, pr.Head.Sha)
+	}
+	return provider.CheckFetched(ctx, dir, refs)
+}
+
+// Feedback posts the result as a comment and a commit status, see Reporter
+func (p *Provider) Feedback(ctx context.Context, ref merger.MergeRef, fb provider.Feedback) error {
+	pr, ok := ref.(mergeRef)
+	if !ok {
+		return provider.ErrForeignRef
+	}
+	return NewReporter(p.Client, p.Repository).Report(ctx, pr.PullRequest, fb)
+}
@@ -0,0 +1,127 @@
+package gitea
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/wayan/mergeexp/selection"
+)
+
+// title prefixes marking work in progress (Gitea default of [repository.pull-request] WORK_IN_PROGRESS_PREFIXES)
+var wipPrefixes = []string{"WIP:", "[WIP]"}
+
+// PullRequest is the subset of the Gitea pull request (also returned by Forgejo) the builds need
+type PullRequest struct {
+	ID        int       `json:"id"`
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Draft     bool      `json:"draft"`
+	Labels    []Label   `json:"labels"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Head      Ref       `json:"head"`
+	Base      Ref       `json:"base"`
+	// web link of the pull request
+	HTMLURL string `json:"html_url"`
+
+	// the deployment directive the pull request was selected by, if any
+	Directive *selection.Directive `json:"-"`
+}
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+type Ref struct {
+	Ref  string      `json:"ref"`
+	Sha  string      `json:"sha"`
+	Repo *Repository `json:"repo"`
+}
+
+type Repository struct {
+	FullName string `json:"full_name"`
+	SSHURL   string `json:"ssh_url"`
+	CloneURL string `json:"clone_url"`
+}
+
+// IsDraft returns true for draft pull request, older servers mark them by the title prefix only
+func (pr *PullRequest) IsDraft() bool {
+	if pr.Draft {
+		return true
+	}
+	title := strings.ToUpper(pr.Title)
+	for _, prefix := range wipPrefixes {
+		if strings.HasPrefix(title, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasLabels returns true if the pull request has all the labels
+func (pr *PullRequest) HasLabels(labels ...string) bool {
+	for _, l := range labels {
+		if !slices.ContainsFunc(pr.Labels, func(pl Label) bool { return pl.Name == l }) {
+			return false
+		}
+	}
+	return true
+}
+
+type mergeRef struct {
+	*PullRequest
+}
+
+func (m mergeRef) Name() string {
+	return fmt.Sprintf("PR %d: %s", m.PullRequest.Number, m.PullRequest.Title)
+}
+
+// Sha is the head of the pull request or the commit pinned by the directive
+func (m mergeRef) Sha() string {
+	if d := m.PullRequest.Directive; d != nil && d.Sha != "" {
+		return d.Sha
+	}
+	return m.PullRequest.Head.Sha
+}
+
+func (m mergeRef) ID() int {
+	return m.PullRequest.Number
+}
+
+func (m mergeRef) CreatedAt() time.Time {
+	return m.PullRequest.CreatedAt
+}
+
+func (m mergeRef) UpdatedAt() time.Time {
+	return m.PullRequest.UpdatedAt
+}
+
+func (m mergeRef) SourceBranch() string {
+	return m.PullRequest.Head.Ref
+}
+
+func (m mergeRef) TargetBranch() string {
+	return m.PullRequest.Base.Ref
+}
+
+// Priority is taken from the directive, 0 if there is none
+func (m mergeRef) Priority() int {
+	if d := m.PullRequest.Directive; d != nil {
+		return d.Priority
+	}
+	return 0
+}
+
+// DependsOn returns the pull requests to be merged before, from the directive
+func (m mergeRef) DependsOn() []int {
+	if d := m.PullRequest.Directive; d != nil {
+		return d.After
+	}
+	return nil
+}
+
+func (pr *PullRequest) MergeRef() mergeRef {
+	return mergeRef{PullRequest: pr}
+}
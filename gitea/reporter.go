@@ -0,0 +1,70 @@
+package gitea
+
+import (
+	"cmp"
+	"context"
+
+	"github.com/wayan/mergeexp/provider"
+)
+
+// Reporter posts the results of the experimental build to the pull requests
+// as a comment (see provider.PostFeedback) and as a commit status of the merged commit
+type Reporter struct {
+	Client *Client
+	// owner/name of the base repository
+	Repository string
+}
+
+func NewReporter(client *Client, repository string) *Reporter {
+	return &Reporter{Client: client, Repository: repository}
+}
+
+// StatusContext is the context of the commit status of the experimental branch,
+// Gitea shows the latest status of each context on the pull request
+func StatusContext(branch string) string {
+	return "mergeexp/" + branch
+}
+
+// Report sets the status of the merged commit in the base repository and posts the comment of the pull request
+func (r *Reporter) Report(ctx context.Context, pr *PullRequest, fb provider.Feedback) error {
+	sha := cmp.Or(fb.Result.SHA, pr.Head.Sha)
+	if err := r.Client.CreateStatus(ctx, r.Repository, sha, FeedbackStatus(pr, fb)); err != nil {
+		return err
+	}
+
+	comments, err := r.Client.Comments(ctx, r.Repository, pr.Number)
+	if err != nil {
+		return err
+	}
+	bodies := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		bodies = append(bodies, comment.Body)
+	}
+	return provider.PostFeedback(bodies, provider.CommentMarker(fb.Branch), fb,
+		func(body string) error {
+			_, err := r.Client.CreateComment(ctx, r.Repository, pr.Number, body)
+			return err
+		},
+		func(i int, body string) error {
+			_, err := r.Client.UpdateComment(ctx, r.Repository, comments[i].ID, body)
+			return err
+		})
+}
+
+// statusStates maps the states of the pull requests onto the commit status states
+var statusStates = map[provider.State]string{
+	provider.StateIncluded: StatusSuccess,
+	provider.StateConflict: StatusFailure,
+	provider.StateExcluded: StatusError,
+}
+
+// FeedbackStatus returns the commit status: in experimental build, conflict or excluded
+func FeedbackStatus(pr *PullRequest, fb provider.Feedback) Status {
+	state, description := provider.FeedbackState(fb)
+	return Status{
+		State:       statusStates[state],
+		TargetURL:   pr.HTMLURL,
+		Description: description,
+		Context:     StatusContext(fb.Branch),
+	}
+}
@@ -0,0 +1,36 @@
+package gitea
+
+import (
+	"context"
+	"fmt"
+)
+
+// states of the commit status
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+	StatusError   = "error"
+	StatusPending = "pending"
+)
+
+// Status is the commit status, the latest status of the context is shown on the pull request
+type Status struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description,omitempty"`
+	Context     string `json:"context"`
+}
+
+// CreateStatus sets the status of the commit in the repository
+func (c *Client) CreateStatus(ctx context.Context, fullname, sha string, status Status) error {
+	resp, err := c.Req(ctx).
+		SetBody(status).
+		Post(fmt.Sprintf("repos/%s/statuses/%s", fullname, sha))
+	if err != nil {
+		return fmt.Errorf("gitea call failed: %w", err)
+	}
+	if !resp.IsSuccess() {
+		return responseError(resp, RepositoryNotFound, fmt.Sprintf("setting status of commit %s", sha))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/wayan/mergeexp/linkheader"
+)
+
+// APIRoot is the root of the public GitHub REST API
+const APIRoot = "https://api.github.com"
+
+type Client struct {
+	restClient *resty.Client
+}
+
+// NewClient expects resty client with base URL (APIRoot or GitHub Enterprise API root)
+// and the authorization header set
+func NewClient(rc *resty.Client) *Client {
+	return &Client{restClient: rc}
+}
+
+func (c *Client) Req(ctx context.Context) *resty.Request {
+	return c.restClient.R().
+		SetContext(ctx).
+		SetHeader("Accept", "application/vnd.github+json")
+}
+
+var (
+	RepositoryNotFound  = errors.New("github repository not found")
+	PullRequestNotFound = errors.New("github pull request not found")
+	CommentNotFound     = errors.New("github comment not found")
+	CommitNotFound      = errors.New("github commit not found")
+	// Unauthorized - the token is missing, invalid or expired
+	Unauthorized = errors.New("github authentication failed")
+)
+
+// responseError returns the error of the failed response,
+// 404 is the missing entity of the endpoint (notFound)
+func responseError(resp *resty.Response, notFound error, action string) error {
+	switch resp.StatusCode() {
+	case http.StatusUnauthorized:
+		return Unauthorized
+	case http.StatusNotFound:
+		return notFound
+	}
+	return fmt.Errorf("%s failed with %s status", action, resp.Status())
+}
+
+// PullRequests returns open, non draft pull requests of the repository (owner/name)
+// against any of the base branches (all if none given) having all the labels
+func (c *Client) PullRequests(ctx context.Context, fullname string, bases []string, labels ...string) ([]PullRequest, error) {
+	var prs []PullRequest
+
+	query := url.Values{}
+	query.Add("state", "open")
+	query.Add("per_page", "100")
+	// single base can be filtered by API
+	if len(bases) == 1 {
+		query.Add("base", bases[0])
+	}
+	u := (&url.URL{
+		Path:     fmt.Sprintf("/repos/%s/pulls", fullname),
+		RawQuery: query.Encode(),
+	}).String()
+
+	// paging
+	for u != "" {
+		var prsPage []PullRequest
+
+		resp, err := c.Req(ctx).SetResult(&prsPage).Get(u)
+		if err != nil {
+			return nil, fmt.Errorf("github failed: %w", err)
+		}
+		if !resp.IsSuccess() {
+			return nil, responseError(resp, RepositoryNotFound, "fetch for GitHub pull requests")
+		}
+
+		for _, pr := range prsPage {
+			if pr.Draft {
+				continue
+			}
+			if len(bases) > 0 && !slices.Contains(bases, pr.Base.Ref) {
+				continue
+			}
+			if !pr.HasLabels(labels...) {
+				continue
+			}
+			prs = append(prs, pr)
+		}
+		u = linkheader.Next(resp.Header().Get("Link"))
+	}
+
+	return prs, nil
+}
+
+// RepositoryCloneURL returns SSH (or HTTPS if ssh is false) clone URL of the repository (owner/name)
+func (c *Client) RepositoryCloneURL(ctx context.Context, fullname string, ssh bool) (string, error) {
+	var repo Repository
+	res, err := c.Req(ctx).
+		SetResult(&repo).
+		Get(fmt.Sprintf("repos/%s", fullname))
+	if err != nil {
+		return "", fmt.Errorf("github call failed: %w", err)
+	}
+	if !res.IsSuccess() {
+		return "", responseError(res, RepositoryNotFound, "fetch for GitHub repository")
+	}
+	return repo.CloneURL(ssh), nil
+}
@@ -0,0 +1,310 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/wayan/mergeexp/gitdir/gitdirtest"
+	"github.com/wayan/mergeexp/merger"
+	"github.com/wayan/mergeexp/provider"
+)
+
+// testClient returns the client of the server with the handler
+func testClient(t *testing.T, handler http.Handler) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewClient(resty.New().SetBaseURL(srv.URL))
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Error(err)
+	}
+}
+
+func pr(number int, base string, draft bool, labels ...string) map[string]any {
+	var ls []map[string]string
+	for _, l := range labels {
+		ls = append(ls, map[string]string{"name": l})
+	}
+	return map[string]any{
+		"number": number,
+		"draft":  draft,
+		"labels": ls,
+		"head":   map[string]string{"ref": fmt.Sprintf("feature-%d", number), "sha": fmt.Sprintf("sha%d", number)},
+		"base":   map[string]string{"ref": base},
+	}
+}
+
+func numbers(prs []PullRequest) []int {
+	var ns []int
+	for _, pr := range prs {
+		ns = append(ns, pr.Number)
+	}
+	return ns
+}
+
+func TestPullRequests(t *testing.T) {
+	var queries []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Query().Get("page") == "2" {
+			writeJSON(t, w, []any{pr(3, "main", false, "exp"), pr(4, "release", false, "exp")})
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<http://%[1]s/repos/o/r/pulls?page=2>; rel="next", <http://%[1]s/repos/o/r/pulls?page=2>; rel="last"`, r.Host))
+		writeJSON(t, w, []any{pr(1, "main", false, "exp", "other"), pr(2, "main", true, "exp"), pr(5, "main", false)})
+	})
+	c := testClient(t, mux)
+
+	tests := []struct {
+		bases  []string
+		labels []string
+		want   []int
+	}{
+		{nil, nil, []int{1, 5, 3, 4}},
+		{nil, []string{"exp"}, []int{1, 3, 4}},
+		{[]string{"main"}, []string{"exp", "other"}, []int{1}},
+		{[]string{"main", "release"}, []string{"exp"}, []int{1, 3, 4}},
+		{[]string{"release"}, nil, []int{4}},
+	}
+	for _, tt := range tests {
+		queries = nil
+		prs, err := c.PullRequests(context.Background(), "o/r", tt.bases, tt.labels...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := numbers(prs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("PullRequests(%v, %v) = %v, want %v", tt.bases, tt.labels, got, tt.want)
+		}
+		if len(queries) != 2 {
+			t.Errorf("%d pages fetched, want 2", len(queries))
+		}
+		// single base is filtered by API
+		if hasBase := strings.Contains(queries[0], "base="); hasBase != (len(tt.bases) == 1) {
+			t.Errorf("query %s for bases %v", queries[0], tt.bases)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/private/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/repos/o/broken/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Server Error"}`, http.StatusInternalServerError)
+	})
+	mux.HandleFunc("POST /repos/o/r/statuses/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"No commit found for SHA: abc"}`, http.StatusUnprocessableEntity)
+	})
+	// everything else is not found
+	c := testClient(t, mux)
+	ctx := context.Background()
+
+	_, err := c.PullRequests(ctx, "o/gone", nil)
+	if !errors.Is(err, RepositoryNotFound) {
+		t.Errorf("PullRequests of missing repository: %v", err)
+	}
+	_, err = c.PullRequests(ctx, "o/private", nil)
+	if !errors.Is(err, Unauthorized) {
+		t.Errorf("PullRequests unauthorized: %v", err)
+	}
+	_, err = c.RepositoryCloneURL(ctx, "o/private", true)
+	if !errors.Is(err, Unauthorized) {
+		t.Errorf("RepositoryCloneURL unauthorized: %v", err)
+	}
+	_, err = c.Comments(ctx, "o/r", 7)
+	if !errors.Is(err, PullRequestNotFound) {
+		t.Errorf("Comments of missing pull request: %v", err)
+	}
+	_, err = c.UpdateComment(ctx, "o/r", 7, "body")
+	if !errors.Is(err, CommentNotFound) {
+		t.Errorf("UpdateComment of missing comment: %v", err)
+	}
+	err = c.CreateStatus(ctx, "o/r", "abc", Status{State: StatusSuccess})
+	if !errors.Is(err, CommitNotFound) {
+		t.Errorf("CreateStatus of missing commit: %v", err)
+	}
+	_, err = c.PullRequests(ctx, "o/broken", nil)
+	if err == nil || err.Error() != "fetch for GitHub pull requests failed with 500 Internal Server Error status" {
+		t.Errorf("PullRequests server error: %v", err)
+	}
+}
+
+func TestComments(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/o/r/issues/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s/repos/o/r/issues/7/comments?page=2>; rel="next"`, r.Host))
+			writeJSON(t, w, []any{map[string]any{"id": 1, "body": "first", "user": map[string]string{"login": "alice"}}})
+			return
+		}
+		writeJSON(t, w, []any{map[string]any{"id": 2, "body": "deployment: exp"}})
+	})
+	c := testClient(t, mux)
+
+	comments, err := c.Comments(context.Background(), "o/r", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 || comments[0].Body != "first" || comments[0].User.Login != "alice" || comments[1].ID != 2 {
+		t.Errorf("comments = %+v", comments)
+	}
+}
+
+func TestCreateStatus(t *testing.T) {
+	var got Status
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /repos/o/r/statuses/abc", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		// headers must be set before the status is written
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(got)
+	})
+	c := testClient(t, mux)
+
+	status := Status{
+		State:       StatusFailure,
+		TargetURL:   "https://github.com/o/r/pull/7",
+		Description: strings.Repeat("ř", 150),
+		Context:     "mergeexp/exp",
+	}
+	if err := c.CreateStatus(context.Background(), "o/r", "abc", status); err != nil {
+		t.Fatal(err)
+	}
+	want := status
+	want.Description = strings.Repeat("ř", 137) + "..."
+	if got != want {
+		t.Errorf("posted status = %+v, want %+v", got, want)
+	}
+}
+
+func TestReporter(t *testing.T) {
+	var statuses []Status
+	comments := []Comment{{ID: 1, Body: "unrelated"}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /repos/o/r/statuses/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		var s Status
+		json.NewDecoder(r.Body).Decode(&s)
+		statuses = append(statuses, s)
+		writeJSON(t, w, s)
+	})
+	mux.HandleFunc("GET /repos/o/r/issues/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, comments)
+	})
+	mux.HandleFunc("POST /repos/o/r/issues/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		var c Comment
+		json.NewDecoder(r.Body).Decode(&c)
+		c.ID = len(comments) + 1
+		comments = append(comments, c)
+		writeJSON(t, w, c)
+	})
+	mux.HandleFunc("PATCH /repos/o/r/issues/comments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var c Comment
+		json.NewDecoder(r.Body).Decode(&c)
+		for i := range comments {
+			if r.PathValue("id") == fmt.Sprint(comments[i].ID) {
+				comments[i].Body = c.Body
+				writeJSON(t, w, comments[i])
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	r := NewReporter(testClient(t, mux), "o/r")
+	pr := &PullRequest{Number: 7, Head: Ref{Sha: "head"}, HTMLURL: "https://github.com/o/r/pull/7"}
+	ctx := context.Background()
+
+	merged := provider.Feedback{Branch: "exp", Commit: "c1", Result: merger.RefReport{SHA: "head", Outcome: merger.OutcomeMerged}}
+	if err := r.Report(ctx, pr, merged); err != nil {
+		t.Fatal(err)
+	}
+	skipped := provider.Feedback{Branch: "exp", Commit: "c2", Result: merger.RefReport{
+		SHA: "head", Outcome: merger.OutcomeSkipped, ConflictPaths: []string{"a.txt", "b.txt"},
+	}}
+	if err := r.Report(ctx, pr, skipped); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(comments) != 2 || comments[1].Body != provider.FeedbackText(provider.CommentMarker("exp"), skipped) {
+		t.Errorf("comments = %+v", comments)
+	}
+	want := []Status{
+		{State: StatusSuccess, TargetURL: pr.HTMLURL, Description: "in experimental build at c1", Context: "mergeexp/exp"},
+		{State: StatusFailure, TargetURL: pr.HTMLURL, Description: "conflict: a.txt, b.txt", Context: "mergeexp/exp"},
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %+v, want %+v", statuses, want)
+	}
+}
+
+func TestChangeRequestsByDirective(t *testing.T) {
+	comments := map[string][]map[string]any{
+		"2": {{"id": 1, "body": "LGTM"}, {"id": 2, "body": "deployment: exp@abcdef1 priority=2 after #1"}},
+		"3": {{"id": 3, "body": "deployment: exp"}, {"id": 4, "body": "deployment: no-exp"}},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("labels") {
+			t.Errorf("labels filtered by API: %s", r.URL.RawQuery)
+		}
+		writeJSON(t, w, []any{pr(1, "main", false, "exp"), pr(2, "main", false), pr(3, "main", false), pr(4, "main", false)})
+	})
+	mux.HandleFunc("GET /repos/o/r/issues/{number}/comments", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, append([]map[string]any{}, comments[r.PathValue("number")]...))
+	})
+	p := NewProvider(testClient(t, mux), "o/r")
+
+	refs, err := p.ChangeRequests(context.Background(), provider.Filter{Labels: []string{"exp"}, Tags: []string{"exp"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 2 || refs[0].(mergeRef).Number != 1 || refs[1].(mergeRef).Number != 2 {
+		t.Fatalf("selected %v", refs)
+	}
+	if labeled := refs[0].(mergeRef); labeled.Sha() != "sha1" || labeled.Priority() != 0 {
+		t.Errorf("labeled pull request: sha %s, priority %d", labeled.Sha(), labeled.Priority())
+	}
+	deployed := refs[1].(mergeRef)
+	if deployed.Sha() != "abcdef1" || deployed.Priority() != 2 || !reflect.DeepEqual(deployed.DependsOn(), []int{1}) {
+		t.Errorf("directive not applied: sha %s, priority %d, after %v", deployed.Sha(), deployed.Priority(), deployed.DependsOn())
+	}
+}
+
+func TestFetch(t *testing.T) {
+	origin := gitdirtest.Repo(t)
+	listed := gitdirtest.Commit(t, origin, "a.txt", "1")
+	gitdirtest.Git(t, origin, "update-ref", "refs/pull/1/head", listed)
+	moved := gitdirtest.Commit(t, origin, "a.txt", "2")
+	gitdirtest.Git(t, origin, "update-ref", "refs/pull/2/head", moved)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/o/r", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, Repository{FullName: "o/r", SSHURL: origin.Dir})
+	})
+	p := NewProvider(testClient(t, mux), "o/r")
+	prs := []PullRequest{{Number: 1, Head: Ref{Sha: listed}}, {Number: 2, Head: Ref{Sha: listed}}}
+	refs := []merger.MergeRef{prs[0].MergeRef(), prs[1].MergeRef()}
+
+	dir := gitdirtest.Repo(t)
+	if err := p.Fetch(context.Background(), dir, refs); err != nil {
+		t.Fatal(err)
+	}
+	if refs[0].Sha() != listed || refs[1].Sha() != moved {
+		t.Errorf("fetched heads %s, %s, want %s and the moved %s", refs[0].Sha(), refs[1].Sha(), listed, moved)
+	}
+}
@@ -0,0 +1,78 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/wayan/mergeexp/linkheader"
+)
+
+// Comment is a comment of a pull request (issue comment)
+type Comment struct {
+	ID   int    `json:"id"`
+	Body string `json:"body"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Comments returns all comments of the pull request, from the oldest
+func (c *Client) Comments(ctx context.Context, fullname string, number int) ([]Comment, error) {
+	var comments []Comment
+
+	u := (&url.URL{
+		Path:     fmt.Sprintf("/repos/%s/issues/%d/comments", fullname, number),
+		RawQuery: url.Values{"per_page": {"100"}}.Encode(),
+	}).String()
+	for u != "" {
+		var commentsPage []Comment
+
+		resp, err := c.Req(ctx).SetResult(&commentsPage).Get(u)
+		if err != nil {
+			return nil, fmt.Errorf("github call failed: %w", err)
+		}
+		if !resp.IsSuccess() {
+			return nil, responseError(resp, PullRequestNotFound, fmt.Sprintf("fetch for comments of pull request #%d", number))
+		}
+
+		comments = append(comments, commentsPage...)
+		u = linkheader.Next(resp.Header().Get("Link"))
+	}
+	return comments, nil
+}
+
+// CreateComment adds a comment to the pull request
+func (c *Client) CreateComment(ctx context.Context, fullname string, number int, body string) (*Comment, error) {
+	var comment Comment
+	resp, err := c.Req(ctx).
+		SetBody(map[string]string{"body": body}).
+		SetResult(&comment).
+		Post(fmt.Sprintf("/repos/%s/issues/%d/comments", fullname, number))
+	if err != nil {
+		return nil, fmt.Errorf("github call failed: %w", err)
+	}
+	if !resp.IsSuccess() {
+		return nil, responseError(resp, PullRequestNotFound, fmt.Sprintf("creating comment of pull request #%d", number))
+	}
+	return &comment, nil
+}
+
+// UpdateComment replaces the body of the comment
+func (c *Client) UpdateComment(ctx context.Context, fullname string, commentID int, body string) (*Comment, error) {
+	var comment Comment
+	resp, err := c.Req(ctx).
+		SetBody(map[string]string{"body": body}).
+		SetResult(&comment).
+		Patch(fmt.Sprintf("/repos/%s/issues/comments/%d", fullname, commentID))
+	if err != nil {
+		return nil, fmt.Errorf("github call failed: %w", err)
+	}
+	if !resp.IsSuccess() {
+		return nil, responseError(resp, CommentNotFound, fmt.Sprintf("updating comment %d", commentID))
+	}
+	return &comment, nil
+}
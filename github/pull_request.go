@@ -0,0 +1,132 @@
+package github
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/wayan/mergeexp/selection"
+)
+
+// PullRequest holds the fields of the GitHub REST pull request used for listing and fetching
+type PullRequest struct {
+	ID        int       `json:"id"`
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Draft     bool      `json:"draft"`
+	Labels    []Label   `json:"labels"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Head      Ref       `json:"head"`
+	Base      Ref       `json:"base"`
+	// web link of the pull request
+	HTMLURL string `json:"html_url"`
+
+	// the deployment directive the pull request was selected by, if any
+	Directive *selection.Directive `json:"-"`
+}
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+type Ref struct {
+	Ref  string      `json:"ref"`
+	Sha  string      `json:"sha"`
+	Repo *Repository `json:"repo"`
+}
+
+type Repository struct {
+	FullName string `json:"full_name"`
+	Fork     bool   `json:"fork"`
+	SSHURL   string `json:"ssh_url"`
+	HTTPSURL string `json:"clone_url"`
+}
+
+// CloneURL returns SSH or HTTPS clone URL
+func (r *Repository) CloneURL(ssh bool) string {
+	if ssh {
+		return r.SSHURL
+	}
+	return r.HTTPSURL
+}
+
+// HasLabels returns true if the pull request has all the labels
+func (pr *PullRequest) HasLabels(labels ...string) bool {
+	for _, l := range labels {
+		if !slices.ContainsFunc(pr.Labels, func(pl Label) bool { return pl.Name == l }) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsFork returns true if the head of the pull request lives in other repository than its base
+func (pr *PullRequest) IsFork() bool {
+	return pr.Head.Repo != nil && pr.Base.Repo != nil && pr.Head.Repo.FullName != pr.Base.Repo.FullName
+}
+
+// SourceCloneURL returns clone URL of the repository with the head of the pull request,
+// empty if the head repository was deleted
+func (pr *PullRequest) SourceCloneURL(ssh bool) string {
+	if pr.Head.Repo == nil {
+		return ""
+	}
+	return pr.Head.Repo.CloneURL(ssh)
+}
+
+type mergeRef struct {
+	*PullRequest
+}
+
+func (m mergeRef) Name() string {
+	return fmt.Sprintf("PR %d: %s", m.PullRequest.Number, m.PullRequest.Title)
+}
+
+// Sha is the head of the pull request or the commit pinned by the directive
+func (m mergeRef) Sha() string {
+	if d := m.PullRequest.Directive; d != nil && d.Sha != "" {
+		return d.Sha
+	}
+	return m.PullRequest.Head.Sha
+}
+
+func (m mergeRef) ID() int {
+	return m.PullRequest.Number
+}
+
+func (m mergeRef) CreatedAt() time.Time {
+	return m.PullRequest.CreatedAt
+}
+
+func (m mergeRef) UpdatedAt() time.Time {
+	return m.PullRequest.UpdatedAt
+}
+
+func (m mergeRef) SourceBranch() string {
+	return m.PullRequest.Head.Ref
+}
+
+func (m mergeRef) TargetBranch() string {
+	return m.PullRequest.Base.Ref
+}
+
+// Priority is taken from the directive, 0 if there is none
+func (m mergeRef) Priority() int {
+	if d := m.PullRequest.Directive; d != nil {
+		return d.Priority
+	}
+	return 0
+}
+
+// DependsOn returns the pull requests to be merged before, from the directive
+func (m mergeRef) DependsOn() []int {
+	if d := m.PullRequest.Directive; d != nil {
+		return d.After
+	}
+	return nil
+}
+
+func (pr *PullRequest) MergeRef() mergeRef {
+	return mergeRef{PullRequest: pr}
+}
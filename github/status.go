@@ -0,0 +1,45 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// states of the commit status
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+	StatusError   = "error"
+	StatusPending = "pending"
+)
+
+// Status is the commit status, the latest status of the context is shown on the pull request
+type Status struct {
+	State     string `json:"state"`
+	TargetURL string `json:"target_url,omitempty"`
+	// GitHub limits the description to 140 characters
+	Description string `json:"description,omitempty"`
+	Context     string `json:"context"`
+}
+
+// CreateStatus sets the status of the commit in the repository
+func (c *Client) CreateStatus(ctx context.Context, fullname, sha string, status Status) error {
+	if r := []rune(status.Description); len(r) > 140 {
+		status.Description = string(r[:137]) + "..."
+	}
+	resp, err := c.Req(ctx).
+		SetBody(status).
+		Post(fmt.Sprintf("/repos/%s/statuses/%s", fullname, sha))
+	if err != nil {
+		return fmt.Errorf("github call failed: %w", err)
+	}
+	if !resp.IsSuccess() {
+		// unknown commit is reported as unprocessable
+		if resp.StatusCode() == http.StatusUnprocessableEntity {
+			return fmt.Errorf("%w: %s", CommitNotFound, sha)
+		}
+		return responseError(resp, RepositoryNotFound, fmt.Sprintf("setting status of commit %s", sha))
+	}
+	return nil
+}
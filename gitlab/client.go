@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/wayan/mergeexp/linkheader"
 )
 
 type Client struct {
@@ -56,32 +57,12 @@ func (c *Client) MergeRequests(ctx context.Context, targetProjectId int, labels
 		}
 
 		mrs = append(mrs, mrsPage...)
-		u = extractNextURL(resp.Header().Get("Link"))
+		u = linkheader.Next(resp.Header().Get("Link"))
 	}
 
 	return mrs, nil
 }
 
-// extractNextURL parses Link header in format RFC 8288: Web Linking and return URL for next link if any
-// header may look like
-// Link: <https://gitlab.example.com/api/v4/projects/9/issues/8/notes?per_page=3&page=1>; rel="prev", <https://gitlab.example.com/api/v4/projects/9/issues/8/notes?per_page=3&page=3>; rel="next", <https://gitlab.example.com/api/v4/projects/9/issues/8/notes?per_page=3&page=1>; rel="first", <https://gitlab.example.com/api/v4/projects/9/issues/8/notes?per_page=3&page=3>; rel="last"
-func extractNextURL(link string) string {
-	// Link header can contain multiple links separated by commas
-	for _, linkPart := range strings.Split(link, ",") {
-		// Each link part looks like: <url>; rel="relation"
-		if strings.Contains(linkPart, `rel="next"`) {
-			// Extract the URL part: it's between '<' and '>'
-			urlStart := strings.Index(linkPart, "<")
-			urlEnd := strings.Index(linkPart, ">")
-
-			if urlStart > -1 && urlEnd > -1 && urlStart < urlEnd {
-				return linkPart[urlStart+1 : urlEnd]
-			}
-		}
-	}
-	return ""
-}
-
 // returns SHA of branch name of project
 func (c *Client) BranchSHA(ctx context.Context, projectID int, name string) (string, error) {
 	var branches []struct {
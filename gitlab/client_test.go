@@ -0,0 +1,55 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+)
+
+func testClient(t *testing.T, handler http.Handler) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewClient(resty.New().SetBaseURL(srv.URL))
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Error(err)
+	}
+}
+
+// projectsHandler serves the projects with the ssh clone urls by id
+func projectsHandler(t *testing.T, urls map[string]string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /projects/{id}", func(w http.ResponseWriter, r *http.Request) {
+		url, ok := urls[r.PathValue("id")]
+		if !ok {
+			http.Error(w, `{"message":"404 Project Not Found"}`, http.StatusNotFound)
+			return
+		}
+		writeJSON(t, w, map[string]string{"ssh_url_to_repo": url})
+	})
+	return mux
+}
+
+func TestProjectSSHUrl(t *testing.T) {
+	c := testClient(t, projectsHandler(t, map[string]string{"1": "git@gitlab.example.com:team/app.git"}))
+	ctx := context.Background()
+
+	url, err := c.ProjectSSHUrl(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "git@gitlab.example.com:team/app.git" {
+		t.Errorf("url = %s", url)
+	}
+	if _, err := c.ProjectSSHUrl(ctx, 2); !errors.Is(err, ProjectNotFound) {
+		t.Errorf("error = %v, want ProjectNotFound", err)
+	}
+}
@@ -0,0 +1,81 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/wayan/mergeexp/gitdir"
+	"github.com/wayan/mergeexp/mirror"
+)
+
+// LocalRefPrefix is the prefix of the local refs the merge request heads are fetched into
+const LocalRefPrefix = "refs/mergeexp/merge-requests/"
+
+// Fetcher fetches the heads of merge requests from their target projects,
+// forks included, as GitLab keeps refs/merge-requests/<iid>/head in the target project
+type Fetcher struct {
+	Client *Client
+	Dir    *gitdir.Dir
+	// the projects are fetched through the mirrors, optional
+	Mirrors *mirror.Cache
+}
+
+func NewFetcher(client *Client, dir *gitdir.Dir) *Fetcher {
+	return &Fetcher{Client: client, Dir: dir}
+}
+
+// MovedMergeRequest is the merge request whose head differs from the Sha it was listed with
+type MovedMergeRequest struct {
+	MergeRequest *MergeRequest
+	// the fetched head
+	Head string
+}
+
+// LocalRef returns the local ref the head of the merge request is fetched into
+func LocalRef(mr *MergeRequest) string {
+	return LocalRefPrefix + strconv.Itoa(mr.IID)
+}
+
+// Fetch fetches refs/merge-requests/<iid>/head of the merge requests (one git fetch per target project)
+// into LocalRef, no remotes are added. The merge requests whose head moved since listing are returned.
+func (f *Fetcher) Fetch(ctx context.Context, mrs []*MergeRequest) ([]MovedMergeRequest, error) {
+	refspecs := map[int][]string{}
+	var projects []int
+	for _, mr := range mrs {
+		if _, ok := refspecs[mr.TargetProjectId]; !ok {
+			projects = append(projects, mr.TargetProjectId)
+		}
+		refspecs[mr.TargetProjectId] = append(refspecs[mr.TargetProjectId],
+			fmt.Sprintf("+refs/merge-requests/%d/head:%s", mr.IID, LocalRef(mr)))
+	}
+
+	for _, projectID := range projects {
+		url, err := f.Client.ProjectSSHUrl(ctx, projectID)
+		if err != nil {
+			return nil, fmt.Errorf("clone url of project %d: %w", projectID, err)
+		}
+		if f.Mirrors != nil {
+			if err := f.Mirrors.Fetch(ctx, f.Dir, url, refspecs[projectID]...); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		args := append([]string{"fetch", "--no-tags", "--prune", "--no-write-fetch-head", url}, refspecs[projectID]...)
+		if _, err := f.Dir.Run(ctx, args...); err != nil {
+			return nil, fmt.Errorf("fetching merge requests from %s: %w", url, err)
+		}
+	}
+
+	var moved []MovedMergeRequest
+	for _, mr := range mrs {
+		head, err := f.Dir.RevParse(ctx, LocalRef(mr))
+		if err != nil {
+			return nil, fmt.Errorf("head of merge request !%d: %w", mr.IID, err)
+		}
+		if head != mr.Sha {
+			moved = append(moved, MovedMergeRequest{MergeRequest: mr, Head: head})
+		}
+	}
+	return moved, nil
+}
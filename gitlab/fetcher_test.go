@@ -0,0 +1,45 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/wayan/mergeexp/gitdir/gitdirtest"
+)
+
+func TestFetch(t *testing.T) {
+	// the merge request from the fork has its head in the target project too
+	target := gitdirtest.Repo(t)
+	listed := gitdirtest.Commit(t, target, "a.txt", "1")
+	gitdirtest.Git(t, target, "update-ref", "refs/merge-requests/1/head", listed)
+	gitdirtest.Git(t, target, "update-ref", "refs/merge-requests/2/head", listed)
+	// force-pushed after listing
+	moved := gitdirtest.Commit(t, target, "a.txt", "2")
+	gitdirtest.Git(t, target, "update-ref", "refs/merge-requests/2/head", moved)
+
+	c := testClient(t, projectsHandler(t, map[string]string{"10": target.Dir}))
+	dir := gitdirtest.Repo(t)
+	mrs := []*MergeRequest{
+		{IID: 1, TargetProjectId: 10, SourceProjectId: 11, Sha: listed},
+		{IID: 2, TargetProjectId: 10, SourceProjectId: 10, Sha: listed},
+	}
+	moves, err := NewFetcher(c, dir).Fetch(context.Background(), mrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 1 || moves[0].MergeRequest != mrs[1] || moves[0].Head != moved {
+		t.Errorf("moved = %+v, want !2 at %s", moves, moved)
+	}
+	if got := gitdirtest.Git(t, dir, "rev-parse", LocalRef(mrs[0])); got != listed {
+		t.Errorf("%s at %s, want %s", LocalRef(mrs[0]), got, listed)
+	}
+	if got := gitdirtest.Git(t, dir, "rev-parse", LocalRef(mrs[1])); got != moved {
+		t.Errorf("%s at %s, want %s", LocalRef(mrs[1]), got, moved)
+	}
+
+	unknown := &MergeRequest{IID: 3, TargetProjectId: 30}
+	if _, err := NewFetcher(c, dir).Fetch(context.Background(), []*MergeRequest{unknown}); !errors.Is(err, ProjectNotFound) {
+		t.Errorf("fetch from unknown project: %v", err)
+	}
+}
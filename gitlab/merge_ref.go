@@ -1,6 +1,14 @@
 package gitlab
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PriorityLabelPrefix marks labels with merge priority, i.e. "priority::10"
+const PriorityLabelPrefix = "priority::"
 
 type mergeRef struct {
 	*MergeRequest
@@ -10,10 +18,58 @@ func (m mergeRef) Name() string {
 	return fmt.Sprintf("MR %d: %s", m.MergeRequest.ID, m.MergeRequest.Title)
 }
 
+// Sha is the head of the merge request or the commit pinned by the directive
 func (m mergeRef) Sha() string {
+	if d := m.MergeRequest.Directive; d != nil && d.Sha != "" {
+		return d.Sha
+	}
 	return m.MergeRequest.Sha
 }
 
+// ID is the project scoped IID (!N), the one referred by the directives
+func (m mergeRef) ID() int {
+	return m.MergeRequest.IID
+}
+
+func (m mergeRef) CreatedAt() time.Time {
+	return m.MergeRequest.CreatedAt
+}
+
+func (m mergeRef) UpdatedAt() time.Time {
+	return m.MergeRequest.UpdatedAt
+}
+
+func (m mergeRef) SourceBranch() string {
+	return m.MergeRequest.SourceBranch
+}
+
+func (m mergeRef) TargetBranch() string {
+	return m.MergeRequest.TargetBranch
+}
+
+// Priority is taken from the directive or from the label "priority::<n>", 0 if there is none
+func (m mergeRef) Priority() int {
+	if d := m.MergeRequest.Directive; d != nil && d.HasPriority {
+		return d.Priority
+	}
+	for _, label := range m.MergeRequest.Labels {
+		if s, ok := strings.CutPrefix(label, PriorityLabelPrefix); ok {
+			if p, err := strconv.Atoi(s); err == nil {
+				return p
+			}
+		}
+	}
+	return 0
+}
+
+// DependsOn returns the merge requests (IIDs) to be merged before, from the directive
+func (m mergeRef) DependsOn() []int {
+	if d := m.MergeRequest.Directive; d != nil {
+		return d.After
+	}
+	return nil
+}
+
 func (mr *MergeRequest) MergeRef() mergeRef {
 	return mergeRef{MergeRequest: mr}
 }
@@ -1,11 +1,25 @@
 package gitlab
 
+import (
+	"time"
+
+	"github.com/wayan/mergeexp/selection"
+)
+
 // minimal info about merge request
 type MergeRequest struct {
-	ID              int    `json:"id"`
-	Sha             string `json:"sha"`
-	SourceProjectId int    `json:"source_project_id"`
-	TargetProjectId int    `json:"target_project_id"`
-	TargetBranch    string `json:"target_branch"`
-	Title           string `json:"title"`
+	ID              int       `json:"id"`
+	IID             int       `json:"iid"`
+	Sha             string    `json:"sha"`
+	SourceProjectId int       `json:"source_project_id"`
+	TargetProjectId int       `json:"target_project_id"`
+	SourceBranch    string    `json:"source_branch"`
+	TargetBranch    string    `json:"target_branch"`
+	Title           string    `json:"title"`
+	Labels          []string  `json:"labels"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	// the deployment directive the merge request was selected by, if any
+	Directive *selection.Directive `json:"-"`
 }
@@ -0,0 +1,79 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/wayan/mergeexp/linkheader"
+)
+
+// Note is a comment of a merge request
+type Note struct {
+	ID     int    `json:"id"`
+	Body   string `json:"body"`
+	System bool   `json:"system"`
+	Author struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MergeRequestNotes returns all notes of the merge request, from the oldest
+func (c *Client) MergeRequestNotes(ctx context.Context, projectID, iid int) ([]Note, error) {
+	var notes []Note
+
+	u := fmt.Sprintf("projects/%d/merge_requests/%d/notes?sort=asc&order_by=created_at&per_page=100", projectID, iid)
+	for u != "" {
+		var notesPage []Note
+
+		resp, err := c.Req(ctx).SetResult(&notesPage).Get(u)
+		if err != nil {
+			return nil, fmt.Errorf("gitlab call failed: %w", err)
+		}
+		if !resp.IsSuccess() {
+			if resp.StatusCode() == http.StatusNotFound {
+				return nil, ProjectNotFound
+			}
+			return nil, fmt.Errorf("fetch for notes of merge request !%d failed with %s status", iid, resp.Status())
+		}
+
+		notes = append(notes, notesPage...)
+		u = linkheader.Next(resp.Header().Get("Link"))
+	}
+	return notes, nil
+}
+
+// CreateMergeRequestNote adds a note to the merge request
+func (c *Client) CreateMergeRequestNote(ctx context.Context, projectID, iid int, body string) (*Note, error) {
+	var note Note
+	resp, err := c.Req(ctx).
+		SetBody(map[string]string{"body": body}).
+		SetResult(&note).
+		Post(fmt.Sprintf("projects/%d/merge_requests/%d/notes", projectID, iid))
+	if err != nil {
+		return nil, fmt.Errorf("gitlab call failed: %w", err)
+	}
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("creating note of merge request !%d failed with %s status", iid, resp.Status())
+	}
+	return &note, nil
+}
+
+// UpdateMergeRequestNote replaces the body of the note of the merge request
+func (c *Client) UpdateMergeRequestNote(ctx context.Context, projectID, iid, noteID int, body string) (*Note, error) {
+	var note Note
+	resp, err := c.Req(ctx).
+		SetBody(map[string]string{"body": body}).
+		SetResult(&note).
+		Put(fmt.Sprintf("projects/%d/merge_requests/%d/notes/%d", projectID, iid, noteID))
+	if err != nil {
+		return nil, fmt.Errorf("gitlab call failed: %w", err)
+	}
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("updating note %d of merge request !%d failed with %s status", noteID, iid, resp.Status())
+	}
+	return &note, nil
+}
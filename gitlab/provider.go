@@ -0,0 +1,122 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/wayan/mergeexp/gitdir"
+	"github.com/wayan/mergeexp/merger"
+	"github.com/wayan/mergeexp/mirror"
+	"github.com/wayan/mergeexp/provider"
+	"github.com/wayan/mergeexp/selection"
+)
+
+// Provider implements provider.Provider for merge requests of the GitLab project
+type Provider struct {
+	Client    *Client
+	ProjectID int
+	// the projects are fetched through the mirrors, optional
+	Mirrors *mirror.Cache
+}
+
+var _ provider.Provider = (*Provider)(nil)
+
+func NewProvider(client *Client, projectID int) *Provider {
+	return &Provider{Client: client, ProjectID: projectID}
+}
+
+// ChangeRequests returns merge requests selected by selection.Policy: labels OR deployment directives in notes
+func (p *Provider) ChangeRequests(ctx context.Context, f provider.Filter) ([]merger.MergeRef, error) {
+	policy := selection.Policy{Labels: f.Labels, Tags: f.Tags}
+	// without tags the labels alone can be filtered by GitLab
+	var labels []string
+	if len(f.Tags) == 0 {
+		labels = f.Labels
+	}
+	mrs, err := p.Client.MergeRequests(ctx, p.ProjectID, labels...)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []merger.MergeRef
+	for i := range mrs {
+		if len(f.TargetBranches) > 0 && !slices.Contains(f.TargetBranches, mrs[i].TargetBranch) {
+			continue
+		}
+		ok, directive, err := policy.Select(mrs[i].Labels, func() ([]string, error) {
+			return p.noteTexts(ctx, mrs[i].IID)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			mrs[i].Directive = directive
+			refs = append(refs, mrs[i].MergeRef())
+		}
+	}
+	return refs, nil
+}
+
+// noteTexts returns bodies of the notes of the merge request (system notes excluded), from the oldest
+func (p *Provider) noteTexts(ctx context.Context, iid int) ([]string, error) {
+	notes, err := p.Client.MergeRequestNotes(ctx, p.ProjectID, iid)
+	if err != nil {
+		return nil, err
+	}
+	var texts []string
+	for _, note := range notes {
+		if !note.System {
+			texts = append(texts, note.Body)
+		}
+	}
+	return texts, nil
+}
+
+func (p *Provider) CloneURL(ctx context.Context, ref merger.MergeRef) (string, error) {
+	mr, ok := ref.(mergeRef)
+	if !ok {
+		return "", provider.ErrForeignRef
+	}
+	return p.Client.ProjectSSHUrl(ctx, mr.SourceProjectId)
+}
+
+// Fetch fetches the heads of the merge requests from the target project, see Fetcher.
+// Merge requests whose head moved since listing (i.e. force-pushed) are reported and their fetched head is merged.
+func (p *Provider) Fetch(ctx context.Context, dir *gitdir.Dir, refs []merger.MergeRef) error {
+	var mrs []*MergeRequest
+	for _, ref := range refs {
+		mr, ok := ref.(mergeRef)
+		if !ok {
+			return provider.ErrForeignRef
+		}
+		mrs = append(mrs, mr.MergeRequest)
+	}
+
+	f := NewFetcher(p.Client, dir)
+	f.Mirrors = p.Mirrors
+	moved, err := f.Fetch(ctx, mrs)
+	if err != nil {
+		return err
+	}
+	for _, m := range moved {
+		slog.Warn(fmt.Sprintf("Merge request !%d moved since listing, merging the fetched head", m.MergeRequest.IID), "listed", m.MergeRequest.Sha, "head", m.Head)
+		// the listed Sha may not be fetched at all
+		m.MergeRequest.Sha = m.Head
+	}
+	for _, ref := range refs {
+		mr := ref.(mergeRef).MergeRequest
+		provider.CheckPinned(ctx, dir, ref.Name(), mr.Directive, mr.Sha)
+	}
+	return provider.CheckFetched(ctx, dir, refs)
+}
+
+// Feedback posts the result as a note of the merge request, see Reporter
+func (p *Provider) Feedback(ctx context.Context, ref merger.MergeRef, fb provider.Feedback) error {
+	mr, ok := ref.(mergeRef)
+	if !ok {
+		return provider.ErrForeignRef
+	}
+	return NewReporter(p.Client, p.ProjectID).Report(ctx, mr.MergeRequest, fb)
+}
@@ -0,0 +1,44 @@
+package gitlab
+
+import (
+	"context"
+
+	"github.com/wayan/mergeexp/provider"
+)
+
+// Reporter posts the results of the experimental build to the merge requests as notes.
+// There is a single note per merge request and experimental branch, it is edited in place on rebuilds.
+type Reporter struct {
+	Client    *Client
+	ProjectID int
+}
+
+func NewReporter(client *Client, projectID int) *Reporter {
+	return &Reporter{Client: client, ProjectID: projectID}
+}
+
+// Report creates or updates the note of the merge request, see provider.PostFeedback
+func (r *Reporter) Report(ctx context.Context, mr *MergeRequest, fb provider.Feedback) error {
+	notes, err := r.Client.MergeRequestNotes(ctx, r.ProjectID, mr.IID)
+	if err != nil {
+		return err
+	}
+	// system notes (i.e. "added 1 commit") are never edited
+	var userNotes []Note
+	var bodies []string
+	for _, note := range notes {
+		if !note.System {
+			userNotes = append(userNotes, note)
+			bodies = append(bodies, note.Body)
+		}
+	}
+	return provider.PostFeedback(bodies, provider.CommentMarker(fb.Branch), fb,
+		func(body string) error {
+			_, err := r.Client.CreateMergeRequestNote(ctx, r.ProjectID, mr.IID, body)
+			return err
+		},
+		func(i int, body string) error {
+			_, err := r.Client.UpdateMergeRequestNote(ctx, r.ProjectID, mr.IID, userNotes[i].ID, body)
+			return err
+		})
+}
@@ -0,0 +1,87 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/wayan/mergeexp/merger"
+	"github.com/wayan/mergeexp/provider"
+)
+
+// fakeNotes serves the notes of the merge request !7 of the project 1, the writes are recorded
+type fakeNotes struct {
+	notes  []Note
+	writes []string
+}
+
+func (f *fakeNotes) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /projects/1/merge_requests/7/notes", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, f.notes)
+	})
+	mux.HandleFunc("POST /projects/1/merge_requests/7/notes", func(w http.ResponseWriter, r *http.Request) {
+		note := Note{ID: 100 + len(f.notes), Body: decodeBody(t, r)}
+		f.notes = append(f.notes, note)
+		f.writes = append(f.writes, "create")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(t, w, note)
+	})
+	mux.HandleFunc("PUT /projects/1/merge_requests/7/notes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		for i := range f.notes {
+			if f.notes[i].ID == id {
+				f.notes[i].Body = decodeBody(t, r)
+				f.writes = append(f.writes, "update "+r.PathValue("id"))
+				writeJSON(t, w, f.notes[i])
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	return mux
+}
+
+func decodeBody(t *testing.T, r *http.Request) string {
+	var body struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Error(err)
+	}
+	return body.Body
+}
+
+func TestReporter(t *testing.T) {
+	marker := provider.CommentMarker("exp")
+	f := &fakeNotes{notes: []Note{
+		{ID: 1, Body: "LGTM"},
+		// system notes are never edited
+		{ID: 2, Body: marker + "\n\nadded 1 commit", System: true},
+		{ID: 3, Body: provider.CommentMarker("other") + "\n\nfeedback of other branch"},
+	}}
+	r := NewReporter(testClient(t, f.handler(t)), 1)
+	mr := &MergeRequest{IID: 7, TargetProjectId: 1}
+	ctx := context.Background()
+
+	merged := provider.Feedback{Branch: "exp", Commit: "c1", Result: merger.RefReport{SHA: "abc", Outcome: merger.OutcomeMerged}}
+	skipped := provider.Feedback{Branch: "exp", Commit: "c2", Result: merger.RefReport{SHA: "abc", Outcome: merger.OutcomeSkipped}}
+	for _, fb := range []provider.Feedback{merged, merged, skipped} {
+		if err := r.Report(ctx, mr, fb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := strings.Join(f.writes, ", "); got != "create, update 103" {
+		t.Errorf("writes %q, want create, then update of the created note", got)
+	}
+	if got, want := f.notes[3].Body, provider.FeedbackText(marker, skipped); got != want {
+		t.Errorf("note = %q, want %q", got, want)
+	}
+	if f.notes[1].Body != marker+"\n\nadded 1 commit" {
+		t.Errorf("system note edited: %q", f.notes[1].Body)
+	}
+}
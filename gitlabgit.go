@@ -26,8 +26,7 @@ func (gg *GitlabGit) RemoteSuggestion(fullname string) string {
 }
 
 func (gg *GitlabGit) Fetch(remote string) error {
-	c := gg.Command("git", "fetch", "--prune", remote)
-	return c.Run()
+	return gg.GitRemotes().Fetch(gg.GitDir(), remote)
 }
 
 func (gg *GitlabGit) GetRemote(fullname string) (string, error) {
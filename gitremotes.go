@@ -1,7 +1,10 @@
 package mergeexp
 
 import (
+	"context"
 	"fmt"
+
+	"github.com/wayan/mergeexp/gitdir"
 )
 
 const GitRemotesPrefix = "macaque"
@@ -15,7 +18,9 @@ func (me *MergeExp) GitRemotes() *GitRemotes {
 }
 
 func (r *GitRemotes) Load() (stringmap, stringmap, error) {
-	lines, err := OutputLines(r.Command("git", "remote"))
+	ctx := context.Background()
+	git := r.GitDir().Git()
+	lines, err := git.Remotes(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -23,7 +28,7 @@ func (r *GitRemotes) Load() (stringmap, stringmap, error) {
 	remoteUrl := make(stringmap)
 	urlRemote := make(stringmap)
 	for _, remote := range lines {
-		url, err := OutputLine(r.Command("git", "remote", "get-url", remote))
+		url, err := git.RemoteURL(ctx, remote)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -75,8 +80,23 @@ func (r *GitRemotes) GetRemote(url string) (string, error) {
 	return urlRemote[url], nil
 }
 
+// Fetch fetches the branches of the remote into refs/remotes/<remote>/, through the mirror
+// of the remote url if the mirror cache is configured
+func (r *GitRemotes) Fetch(dir *gitdir.Dir, remote string) error {
+	ctx := context.Background()
+	if r.Mirrors == nil {
+		_, err := dir.Run(ctx, "fetch", "--prune", remote)
+		return err
+	}
+	url, err := dir.Git().RemoteURL(ctx, remote)
+	if err != nil {
+		return err
+	}
+	return r.Mirrors.Fetch(ctx, dir, url, "+refs/heads/*:refs/remotes/"+remote+"/*")
+}
+
 func (r *GitRemotes) add(remote string, url string) (string, error) {
-	err := r.Command("git", "remote", "add", remote, url).Run()
+	err := r.GitDir().Git().RemoteAdd(context.Background(), remote, url)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,23 @@
+// Package linkheader parses the Link header (RFC 8288: Web Linking) used for paging by the REST APIs
+// of GitLab and GitHub.
+package linkheader
+
+import "strings"
+
+// Next returns the URL of the rel="next" link of the header, empty if there is none
+func Next(link string) string {
+	// Link header can contain multiple links separated by commas
+	for _, linkPart := range strings.Split(link, ",") {
+		// Each link part looks like: <url>; rel="relation"
+		if strings.Contains(linkPart, `rel="next"`) {
+			// Extract the URL part: it's between '<' and '>'
+			urlStart := strings.Index(linkPart, "<")
+			urlEnd := strings.Index(linkPart, ">")
+
+			if urlStart > -1 && urlEnd > -1 && urlStart < urlEnd {
+				return linkPart[urlStart+1 : urlEnd]
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package merger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictPolicy decides what happens when a merge leaves unmerged files
+// which could not be resolved by rerere
+type ConflictPolicy int
+
+const (
+	// ConflictInteractive invokes bash for manual conflict resolution
+	ConflictInteractive ConflictPolicy = iota
+	// ConflictSkip aborts the merge of the conflicting ref and continues with the rest
+	ConflictSkip
+	// ConflictFail aborts the merge and returns *ConflictError
+	ConflictFail
+)
+
+var conflictPolicyNames = map[ConflictPolicy]string{
+	ConflictInteractive: "interactive",
+	ConflictSkip:        "skip",
+	ConflictFail:        "fail",
+}
+
+func (p ConflictPolicy) String() string {
+	if name, ok := conflictPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("ConflictPolicy(%d)", int(p))
+}
+
+// ParseConflictPolicy converts the name of the policy ("interactive", "skip", "fail")
+// to ConflictPolicy
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	for p, name := range conflictPolicyNames {
+		if name == s {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown conflict policy '%s'", s)
+}
+
+// ConflictError is returned by MergeBranches with ConflictFail policy
+type ConflictError struct {
+	Ref   MergeRef
+	Paths []string
+	// names of previously merged refs causing the conflict, filled with Merger.Bisect
+	Culprits []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflict when merging %s (%s)%s in: %s", e.Ref.Name(), e.Ref.Sha(), culpritsInfo(e.Culprits), strings.Join(e.Paths, ", "))
+}
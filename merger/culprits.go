@@ -0,0 +1,133 @@
+package merger
+
+import (
+	"context"
+	"strings"
+)
+
+// FindCulprits finds the minimal subset of already merged refs which makes ref conflict,
+// using delta debugging over merged with in-memory merges (git merge-tree) onto base.
+// Returns empty slice if ref conflicts with base alone, nil if the conflict cannot be reproduced.
+func (m *Merger) FindCulprits(ctx context.Context, base string, merged []MergeRef, ref MergeRef) ([]MergeRef, error) {
+	var testErr error
+	conflicts := func(subset []MergeRef) bool {
+		if testErr != nil {
+			return false
+		}
+		if err := ctx.Err(); err != nil {
+			testErr = err
+			return false
+		}
+		ok, err := m.conflictsAfter(ctx, base, subset, ref)
+		if err != nil {
+			testErr = err
+		}
+		return ok
+	}
+
+	if conflicts(nil) {
+		return []MergeRef{}, testErr
+	}
+	if !conflicts(merged) {
+		return nil, testErr
+	}
+
+	culprits := ddmin(merged, conflicts)
+	if testErr != nil {
+		return nil, testErr
+	}
+	return culprits, nil
+}
+
+// conflictsAfter tests whether ref conflicts after merging of the refs onto base,
+// refs conflicting on the way are skipped
+func (m *Merger) conflictsAfter(ctx context.Context, base string, refs []MergeRef, ref MergeRef) (bool, error) {
+	head := base
+	for _, r := range refs {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		_, commit, err := m.simulateMerge(ctx, head, r)
+		if err != nil {
+			return false, err
+		}
+		if commit != "" {
+			head = commit
+		}
+	}
+	result, err := m.dir.MergeTree(ctx, head, ref.Sha())
+	if err != nil {
+		return false, err
+	}
+	return !result.Clean(), nil
+}
+
+// ddmin is the minimizing delta debugging algorithm, items must satisfy the test,
+// the result is a 1-minimal subset (keeping the order) still satisfying the test
+func ddmin[T any](items []T, test func([]T) bool) []T {
+	n := 2
+	for len(items) >= 2 {
+		chunks := splitChunks(items, n)
+		reduced := false
+
+		// reduce to subset
+		for _, chunk := range chunks {
+			if test(chunk) {
+				items, n, reduced = chunk, 2, true
+				break
+			}
+		}
+
+		// reduce to complement
+		if !reduced && n > 2 {
+			for i := range chunks {
+				var complement []T
+				for j, chunk := range chunks {
+					if j != i {
+						complement = append(complement, chunk...)
+					}
+				}
+				if test(complement) {
+					items, n, reduced = complement, max(n-1, 2), true
+					break
+				}
+			}
+		}
+
+		if !reduced {
+			if n >= len(items) {
+				break
+			}
+			n = min(2*n, len(items))
+		}
+	}
+	return items
+}
+
+// splitChunks splits items into n chunks of nearly equal size
+func splitChunks[T any](items []T, n int) [][]T {
+	var chunks [][]T
+	start := 0
+	for i := 0; i < n; i++ {
+		end := start + (len(items)-start)/(n-i)
+		chunks = append(chunks, items[start:end])
+		start = end
+	}
+	return chunks
+}
+
+// joinNames joins names as "A", "A and B", "A, B and C"
+func joinNames(names []string) string {
+	if len(names) <= 1 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+func refNames(refs []MergeRef) []string {
+	names := make([]string, 0, len(refs))
+	for _, r := range refs {
+		names = append(names, r.Name())
+	}
+	return names
+}
@@ -0,0 +1,136 @@
+package merger
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wayan/mergeexp/gitdir/gitdirtest"
+)
+
+func TestDdmin(t *testing.T) {
+	// test passes when the subset contains all the needed items
+	containsAll := func(needed ...int) func([]int) bool {
+		return func(items []int) bool {
+			for _, n := range needed {
+				if !slices.Contains(items, n) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	tests := []struct {
+		name  string
+		items []int
+		test  func([]int) bool
+		want  []int
+	}{
+		{"single culprit", []int{1, 2, 3, 4, 5, 6, 7, 8}, containsAll(6), []int{6}},
+		{"first", []int{1, 2, 3, 4, 5}, containsAll(1), []int{1}},
+		{"last", []int{1, 2, 3, 4, 5}, containsAll(5), []int{5}},
+		{"two apart", []int{1, 2, 3, 4, 5, 6, 7, 8}, containsAll(2, 7), []int{2, 7}},
+		{"three", []int{1, 2, 3, 4, 5, 6, 7}, containsAll(1, 4, 7), []int{1, 4, 7}},
+		{"all needed", []int{1, 2, 3}, containsAll(1, 2, 3), []int{1, 2, 3}},
+		{"one item", []int{1}, containsAll(1), []int{1}},
+		{"either of two", []int{1, 2, 3, 4}, func(items []int) bool {
+			return slices.Contains(items, 2) || slices.Contains(items, 4)
+		}, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			got := ddmin(tt.items, func(items []int) bool {
+				calls++
+				return tt.test(items)
+			})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ddmin = %v, want %v", got, tt.want)
+			}
+			if calls > len(tt.items)*len(tt.items) {
+				t.Errorf("%d tests of %d items", calls, len(tt.items))
+			}
+		})
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	got := splitChunks([]int{1, 2, 3, 4, 5}, 3)
+	want := [][]int{{1}, {2, 3}, {4, 5}}
+	if len(got) != len(want) {
+		t.Fatalf("splitChunks = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !slices.Equal(got[i], want[i]) {
+			t.Errorf("splitChunks = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFindCulprits(t *testing.T) {
+	tests := []struct {
+		name     string
+		culprits []string
+		want     []string
+	}{
+		{"single", []string{"c"}, []string{"C"}},
+		{"pair", []string{"a", "d"}, []string{"A", "D"}},
+		{"base", []string{}, []string{}},
+		{"not reproduced", []string{"z"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &gitdirtest.Backend{Conflict: conflictsWith(map[string][]string{"x": tt.culprits})}
+			m := newTestMerger(t, b)
+
+			culprits, err := m.FindCulprits(context.Background(), "base", testRefs("a", "b", "c", "d", "e"), testRef{"X", "x"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if culprits != nil {
+					t.Errorf("culprits = %v, want nil", refNames(culprits))
+				}
+				return
+			}
+			if got := refNames(culprits); !slices.Equal(got, tt.want) {
+				t.Errorf("culprits = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindCulpritsCancelled(t *testing.T) {
+	b := &gitdirtest.Backend{Conflict: conflictsWith(map[string][]string{"x": {"c"}})}
+	m := newTestMerger(t, b)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := m.FindCulprits(ctx, "base", testRefs("a", "b", "c"), testRef{"X", "x"}); err == nil {
+		t.Error("cancelled bisection succeeded")
+	}
+}
+
+func TestMergeBranchesBisect(t *testing.T) {
+	b := &gitdirtest.Backend{Conflict: conflictsWith(map[string][]string{"d": {"b"}})}
+	m := newTestMerger(t, b)
+	m.ConflictPolicy = ConflictSkip
+	m.Bisect = true
+
+	report, err := m.MergeBranches(context.Background(), testRefs("a", "b", "c", "d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := report.Refs[3].Culprits; !slices.Equal(got, []string{"B"}) {
+		t.Errorf("culprits = %v, want [B]", got)
+	}
+}
+
+func TestJoinNames(t *testing.T) {
+	for n, want := range []string{"", "A", "A and B", "A, B and C"} {
+		if got := joinNames(strings.Split("ABC", "")[:n]); got != want {
+			t.Errorf("joinNames of %d = %q, want %q", n, got, want)
+		}
+	}
+}
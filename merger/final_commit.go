@@ -0,0 +1,24 @@
+package merger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FinalCommit creates an (empty) commit on top of the merged branches,
+// the message is followed by the summary of the report.
+// The report is recorded as a note of the commit for the incremental rebuild.
+func (m *Merger) FinalCommit(ctx context.Context, message string, report *MergeReport) error {
+	message = strings.TrimRight(message, "\n")
+	if report != nil && len(report.Refs) > 0 {
+		message += "\n\n" + report.Summary()
+	}
+	if err := m.dir.Git().Commit(ctx, message, true); err != nil {
+		return fmt.Errorf("final commit: %w", err)
+	}
+	if report != nil {
+		return m.RecordReport(ctx, "HEAD", report)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package merger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// NotesRef is the notes ref where the reports of the builds are recorded
+const NotesRef = "refs/notes/mergeexp"
+
+// RemoteNotesRef is the notes ref the NotesRef of the remote is fetched into, the local notes are kept
+func RemoteNotesRef(remote string) string {
+	return "refs/notes/remotes/" + remote + "/mergeexp"
+}
+
+// RecordReport attaches the report to the commit as a git note (in NotesRef)
+func (m *Merger) RecordReport(ctx context.Context, rev string, report *MergeReport) error {
+	b, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+	if err := m.dir.Git().AddNote(ctx, NotesRef, rev, string(b)); err != nil {
+		return fmt.Errorf("recording report of %s: %w", rev, err)
+	}
+	return nil
+}
+
+// LoadReport reads the report recorded by RecordReport, returns nil if there is none
+func (m *Merger) LoadReport(ctx context.Context, rev string) (*MergeReport, error) {
+	return m.LoadReportFrom(ctx, NotesRef, rev)
+}
+
+// LoadReportFrom is LoadReport reading the notes in notesRef, i.e. RemoteNotesRef
+func (m *Merger) LoadReportFrom(ctx context.Context, notesRef, rev string) (*MergeReport, error) {
+	if _, err := m.dir.RevParse(ctx, rev); err != nil {
+		return nil, nil
+	}
+	note, err := m.dir.Git().Note(ctx, notesRef, rev)
+	if err != nil {
+		return nil, fmt.Errorf("reading report of %s: %w", rev, err)
+	}
+	if note == "" {
+		return nil, nil
+	}
+
+	var report MergeReport
+	if err := json.Unmarshal([]byte(note), &report); err != nil {
+		return nil, fmt.Errorf("parsing report of %s: %w", rev, err)
+	}
+	return &report, nil
+}
+
+// ReusablePrefix returns the number of leading refs merged in the same way by the previous build
+// and the commit after merging them. It returns 0 when the base moved.
+func (prev *MergeReport) ReusablePrefix(base string, refs []MergeRef) (int, string) {
+	if prev == nil || prev.Base != base {
+		return 0, base
+	}
+
+	n, commit := 0, base
+	for n < len(refs) && n < len(prev.Refs) {
+		rr := prev.Refs[n]
+		if rr.Name != refs[n].Name() || rr.SHA != refs[n].Sha() || rr.Outcome == OutcomeFailed || rr.MergeCommit == "" {
+			break
+		}
+		n, commit = n+1, rr.MergeCommit
+	}
+	return n, commit
+}
+
+// MergeIncremental is MergeBranches reusing the previous build: HEAD is reset to the merge commit
+// of the longest prefix of refs unchanged since prev and only the rest is merged.
+// It is the full merge when prev is nil or the base (current HEAD) moved.
+func (m *Merger) MergeIncremental(ctx context.Context, prev *MergeReport, branches []MergeRef) (*MergeReport, error) {
+	branches, err := m.order(ctx, branches)
+	if err != nil {
+		return &MergeReport{}, err
+	}
+
+	base, err := m.dir.RevParse(ctx, "HEAD")
+	if err != nil {
+		return &MergeReport{}, err
+	}
+
+	st := newState(base, branches)
+	n, commit := prev.ReusablePrefix(base, branches)
+	if n > 0 && !m.dir.ShaExists(ctx, commit) {
+		slog.Warn(fmt.Sprintf("Merge commit %s of the previous build not found, full rebuild", commit))
+		n = 0
+	}
+	if n > 0 {
+		slog.Info(fmt.Sprintf("Reusing %d of %d merges of the previous build", n, len(branches)))
+		if err := m.dir.Git().ResetHard(ctx, commit); err != nil {
+			return &MergeReport{}, fmt.Errorf("resetting to %s: %w", commit, err)
+		}
+		st.Head = commit
+		st.Done = append(st.Done, prev.Refs[:n]...)
+	} else if prev != nil {
+		slog.Info("Nothing to reuse from the previous build, full rebuild")
+	}
+
+	if err := m.saveState(ctx, st); err != nil {
+		return &MergeReport{}, err
+	}
+	return m.mergeFrom(ctx, st, false)
+}
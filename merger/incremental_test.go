@@ -0,0 +1,119 @@
+package merger
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wayan/mergeexp/gitdir/gitdirtest"
+)
+
+func TestReusablePrefix(t *testing.T) {
+	prev := &MergeReport{Base: "base", Refs: []RefReport{
+		{Name: "A", SHA: "a", Outcome: OutcomeMerged, MergeCommit: "m1"},
+		{Name: "B", SHA: "b", Outcome: OutcomeSkipped, MergeCommit: "m1"},
+		{Name: "C", SHA: "c", Outcome: OutcomeFailed, MergeCommit: "m1"},
+	}}
+	tests := []struct {
+		name       string
+		prev       *MergeReport
+		base       string
+		refs       []MergeRef
+		wantN      int
+		wantCommit string
+	}{
+		{"no previous build", nil, "base", testRefs("a", "b"), 0, "base"},
+		{"base moved", prev, "base2", testRefs("a", "b"), 0, "base2"},
+		{"unchanged up to the failed", prev, "base", testRefs("a", "b", "c"), 2, "m1"},
+		{"ref added", prev, "base", testRefs("a", "b"), 2, "m1"},
+		{"head moved", prev, "base", append(testRefs("a"), testRef{"B", "b2"}), 1, "m1"},
+		{"ref removed", prev, "base", testRefs("b"), 0, "base"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, commit := tt.prev.ReusablePrefix(tt.base, tt.refs)
+			if n != tt.wantN || commit != tt.wantCommit {
+				t.Errorf("ReusablePrefix = %d, %s, want %d, %s", n, commit, tt.wantN, tt.wantCommit)
+			}
+		})
+	}
+}
+
+func TestMergeIncremental(t *testing.T) {
+	b := &gitdirtest.Backend{}
+	m := newTestMerger(t, b)
+	ctx := context.Background()
+
+	prev, err := m.MergeBranches(ctx, testRefs("a", "b", "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		prev *MergeReport
+		refs []MergeRef
+		// the merges done
+		want []string
+	}{
+		{"head of C moved", prev, append(testRefs("a", "b"), testRef{"C", "c2"}), []string{"c2"}},
+		{"head of A moved", prev, append([]MergeRef{testRef{"A", "a2"}}, testRefs("b", "c")...), []string{"a2", "b", "c"}},
+		{"no previous build", nil, testRefs("a", "b", "c"), []string{"a", "b", "c"}},
+		{
+			"merge commit gone",
+			&MergeReport{Base: "base", Refs: []RefReport{{Name: "A", SHA: "a", Outcome: OutcomeMerged, MergeCommit: "gone"}}},
+			testRefs("a", "b"),
+			[]string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b.ResetHard(ctx, "base")
+			b.Calls = nil
+
+			report, err := m.MergeIncremental(ctx, tt.prev, tt.refs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var merges []string
+			for _, c := range b.Calls {
+				if sha, ok := strings.CutPrefix(c, "merge "); ok {
+					merges = append(merges, sha)
+				}
+			}
+			if !slices.Equal(merges, tt.want) {
+				t.Errorf("merged %v, want %v", merges, tt.want)
+			}
+			if got := outcomes(report); len(got) != len(tt.refs) || slices.ContainsFunc(got, func(o Outcome) bool { return o != OutcomeMerged }) {
+				t.Errorf("outcomes = %v", got)
+			}
+			var shas []string
+			for _, ref := range tt.refs {
+				shas = append(shas, ref.Sha())
+			}
+			if got := b.Merged("HEAD"); !slices.Equal(got, shas) {
+				t.Errorf("HEAD merged %v, want %v", got, shas)
+			}
+		})
+	}
+}
+
+func TestLoadReportFrom(t *testing.T) {
+	b := &gitdirtest.Backend{Notes: map[string]string{
+		RemoteNotesRef("origin") + ":base": `{"base":"base"}`,
+	}}
+	m := newTestMerger(t, b)
+	ctx := context.Background()
+
+	if prev, err := m.LoadReport(ctx, "HEAD"); err != nil || prev != nil {
+		t.Fatalf("LoadReport of the remote note = %v, %v", prev, err)
+	}
+	prev, err := m.LoadReportFrom(ctx, RemoteNotesRef("origin"), "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev == nil || prev.Base != "base" {
+		t.Errorf("LoadReportFrom = %+v", prev)
+	}
+}
@@ -0,0 +1,202 @@
+package merger
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// MatrixRef is a ref of the conflict matrix
+type MatrixRef struct {
+	Name string `json:"name"`
+	SHA  string `json:"sha"`
+	// paths conflicting when merging the ref alone onto the base
+	BaseConflicts []string `json:"base_conflicts,omitempty"`
+}
+
+// ConflictMatrix contains pairwise conflicts between refs, each pair is merged onto the base
+type ConflictMatrix struct {
+	Base string      `json:"base"`
+	Refs []MatrixRef `json:"refs"`
+	// Conflicts[i][j] are the paths conflicting between Refs[i] and Refs[j],
+	// the matrix is symmetric, the diagonal is empty
+	Conflicts [][][]string `json:"conflicts"`
+}
+
+// ConflictPair is a pair of conflicting refs
+type ConflictPair struct {
+	A, B  MatrixRef
+	Paths []string
+}
+
+// ConflictMatrix computes pairwise conflicts of the refs using git merge-tree.
+// A ref conflicting with the base itself has no conflicts with the other refs.
+func (m *Merger) ConflictMatrix(ctx context.Context, base string, refs []MergeRef) (*ConflictMatrix, error) {
+	baseSha, err := m.dir.RevParse(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(refs)
+	cm := &ConflictMatrix{
+		Base:      baseSha,
+		Refs:      make([]MatrixRef, n),
+		Conflicts: make([][][]string, n),
+	}
+
+	// each ref merged alone onto the base
+	commits := make([]string, n)
+	for i, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		cm.Conflicts[i] = make([][]string, n)
+		result, commit, err := m.simulateMerge(ctx, baseSha, ref)
+		if err != nil {
+			return nil, err
+		}
+		cm.Refs[i] = MatrixRef{Name: ref.Name(), SHA: ref.Sha(), BaseConflicts: result.Conflicts}
+		commits[i] = commit
+	}
+
+	for i := range refs {
+		if commits[i] == "" {
+			continue
+		}
+		for j := i + 1; j < n; j++ {
+			if commits[j] == "" {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			result, err := m.dir.MergeTree(ctx, commits[i], refs[j].Sha())
+			if err != nil {
+				return nil, err
+			}
+			cm.Conflicts[i][j] = result.Conflicts
+			cm.Conflicts[j][i] = result.Conflicts
+		}
+	}
+	return cm, nil
+}
+
+// Pairs returns all conflicting pairs
+func (cm *ConflictMatrix) Pairs() []ConflictPair {
+	var pairs []ConflictPair
+	for i := range cm.Refs {
+		for j := i + 1; j < len(cm.Refs); j++ {
+			if paths := cm.Conflicts[i][j]; len(paths) > 0 {
+				pairs = append(pairs, ConflictPair{A: cm.Refs[i], B: cm.Refs[j], Paths: paths})
+			}
+		}
+	}
+	return pairs
+}
+
+// WriteText writes the matrix as a table (X marks a conflict, B a conflict with the base)
+// followed by the list of the conflicting pairs
+func (cm *ConflictMatrix) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("    ")
+	for j := range cm.Refs {
+		fmt.Fprintf(&sb, "%3d", j+1)
+	}
+	sb.WriteString("\n")
+	for i, ref := range cm.Refs {
+		fmt.Fprintf(&sb, "%3d ", i+1)
+		for j := range cm.Refs {
+			fmt.Fprintf(&sb, "%3s", cm.mark(i, j))
+		}
+		fmt.Fprintf(&sb, "  %s %s\n", shortSha(ref.SHA), ref.Name)
+	}
+
+	sb.WriteString("\n")
+	for _, ref := range cm.Refs {
+		if len(ref.BaseConflicts) > 0 {
+			fmt.Fprintf(&sb, "%s conflicts with the base on %s\n", ref.Name, strings.Join(ref.BaseConflicts, ", "))
+		}
+	}
+	for _, p := range cm.Pairs() {
+		fmt.Fprintf(&sb, "%s collides with %s on %s\n", p.A.Name, p.B.Name, strings.Join(p.Paths, ", "))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// mark returns the symbol of the cell in text and HTML output
+func (cm *ConflictMatrix) mark(i, j int) string {
+	switch {
+	case i == j && len(cm.Refs[i].BaseConflicts) > 0:
+		return "B"
+	case i == j:
+		return "-"
+	case len(cm.Conflicts[i][j]) > 0:
+		return "X"
+	}
+	return "."
+}
+
+var matrixHTML = template.Must(template.New("matrix").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Conflict matrix</title>
+<style>
+table { border-collapse: collapse; font-family: sans-serif; font-size: 13px; }
+th, td { border: 1px solid #ccc; padding: 4px 6px; text-align: center; }
+th.name { text-align: left; }
+td.conflict { background: #f4a6a6; }
+td.base { background: #f7d08a; }
+</style>
+</head>
+<body>
+<h1>Conflict matrix</h1>
+<p>Base {{ .Base }}</p>
+<table>
+<tr><th></th><th></th>{{ range .Rows }}<th>{{ .Index }}</th>{{ end }}</tr>
+{{ range .Rows }}<tr><th>{{ .Index }}</th><th class="name">{{ .Ref.Name }}</th>{{ range .Cells }}<td class="{{ .Class }}" title="{{ .Title }}">{{ .Mark }}</td>{{ end }}</tr>
+{{ end }}</table>
+</body>
+</html>
+`))
+
+type htmlCell struct {
+	Class, Title, Mark string
+}
+
+type htmlRow struct {
+	Index int
+	Ref   MatrixRef
+	Cells []htmlCell
+}
+
+// WriteHTML writes the matrix as a HTML page, conflicting paths are in the titles of the cells
+func (cm *ConflictMatrix) WriteHTML(w io.Writer) error {
+	var rows []htmlRow
+	for i, ref := range cm.Refs {
+		row := htmlRow{Index: i + 1, Ref: ref}
+		for j := range cm.Refs {
+			cell := htmlCell{Mark: cm.mark(i, j)}
+			switch cell.Mark {
+			case "B":
+				cell.Class = "base"
+				cell.Title = "conflicts with the base: " + strings.Join(ref.BaseConflicts, ", ")
+			case "X":
+				cell.Class = "conflict"
+				cell.Title = strings.Join(cm.Conflicts[i][j], ", ")
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		rows = append(rows, row)
+	}
+
+	data := struct {
+		Base string
+		Rows []htmlRow
+	}{cm.Base, rows}
+	return matrixHTML.Execute(w, data)
+}
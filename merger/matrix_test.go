@@ -0,0 +1,91 @@
+package merger
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wayan/mergeexp/gitdir/gitdirtest"
+)
+
+func TestConflictMatrix(t *testing.T) {
+	// B collides with A, C with B, D conflicts with the base, E is clean
+	b := &gitdirtest.Backend{Conflict: conflictsWith(map[string][]string{
+		"b": {"a"},
+		"c": {"b"},
+		"d": nil,
+	})}
+	m := newTestMerger(t, b)
+
+	cm, err := m.ConflictMatrix(context.Background(), "base", testRefs("a", "b", "c", "d", "e"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cm.Base != "base" {
+		t.Errorf("base = %s", cm.Base)
+	}
+
+	tests := []struct {
+		i, j int
+		mark string
+	}{
+		{0, 1, "X"},
+		{1, 0, "X"},
+		{1, 2, "X"},
+		{2, 1, "X"},
+		{0, 2, "."},
+		{0, 4, "."},
+		{2, 4, "."},
+		// no pairs with the ref conflicting with the base
+		{0, 3, "."},
+		{3, 1, "."},
+		{0, 0, "-"},
+		{3, 3, "B"},
+	}
+	for _, tt := range tests {
+		if got := cm.mark(tt.i, tt.j); got != tt.mark {
+			t.Errorf("cell %s/%s = %s, want %s", cm.Refs[tt.i].Name, cm.Refs[tt.j].Name, got, tt.mark)
+		}
+	}
+	if got := cm.Conflicts[0][1]; !slices.Equal(got, []string{"f.txt"}) {
+		t.Errorf("conflicts of A and B = %v", got)
+	}
+	if got := cm.Refs[3].BaseConflicts; !slices.Equal(got, []string{"f.txt"}) {
+		t.Errorf("base conflicts of D = %v", got)
+	}
+
+	var pairs []string
+	for _, p := range cm.Pairs() {
+		pairs = append(pairs, p.A.Name+p.B.Name)
+	}
+	if !slices.Equal(pairs, []string{"AB", "BC"}) {
+		t.Errorf("pairs = %v, want [AB BC]", pairs)
+	}
+}
+
+func TestConflictMatrixWriteText(t *testing.T) {
+	cm := &ConflictMatrix{
+		Base: "base",
+		Refs: []MatrixRef{{Name: "A", SHA: "a"}, {Name: "B", SHA: "b"}, {Name: "C", SHA: "c", BaseConflicts: []string{"g.txt"}}},
+		Conflicts: [][][]string{
+			{nil, {"f.txt"}, nil},
+			{{"f.txt"}, nil, nil},
+			{nil, nil, nil},
+		},
+	}
+	var sb strings.Builder
+	if err := cm.WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := "      1  2  3\n" +
+		"  1   -  X  .  a A\n" +
+		"  2   X  -  .  b B\n" +
+		"  3   .  .  B  c C\n" +
+		"\n" +
+		"C conflicts with the base on g.txt\n" +
+		"A collides with B on f.txt\n"
+	if got := sb.String(); got != want {
+		t.Errorf("WriteText =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -1,77 +1,264 @@
 package merger
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"log/slog"
 )
 
-func (m *Merger) MergeBranches(branches []MergeRef) error {
-	for i, b := range branches {
-		slog.Info(fmt.Sprintf("Merging %d of %d (%s)", i+1, len(branches), b.Name()))
-		if err := m.mergeBranch(b, i, len(branches)); err != nil {
-			return err
+// MergeBranches merges all branches one by one into the current branch.
+// The report is returned even on error, containing the refs processed so far.
+// The progress is persisted in the git dir, so that an interrupted sequence can be resumed (see Resume).
+// On cancellation of the context the merge in progress is aborted and the context error returned.
+func (m *Merger) MergeBranches(ctx context.Context, branches []MergeRef) (*MergeReport, error) {
+	return m.MergeIncremental(ctx, nil, branches)
+}
+
+// mergeFrom merges the refs of the state not processed yet,
+// inMerge means the merge of the first of them is in progress
+func (m *Merger) mergeFrom(ctx context.Context, st *State, inMerge bool) (*MergeReport, error) {
+	report := &MergeReport{Base: st.Base, Refs: slices.Clone(st.Done)}
+
+	m.base, m.merged = st.Base, nil
+	for i, rr := range st.Done {
+		if rr.Outcome.Included() {
+			m.merged = append(m.merged, st.Refs[i])
 		}
 	}
-	return nil
+
+	n := len(st.Refs)
+	for i := len(st.Done); i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return report, fmt.Errorf("merging interrupted, resume to continue: %w", err)
+		}
+		b := st.Refs[i]
+		slog.Info(fmt.Sprintf("Merging %d of %d (%s)", i+1, n, b.Name()))
+
+		started := time.Now()
+		rr := RefReport{Name: b.Name(), SHA: b.Sha()}
+		var err error
+		if inMerge {
+			err = m.resolveConflict(ctx, b, &rr, mergeMessage(b), 1, i, n)
+			inMerge = false
+		} else {
+			err = m.mergeBranch(ctx, b, &rr, i, n)
+		}
+		if err != nil && ctx.Err() != nil {
+			// the merge was aborted, the ref is left for resume
+			return report, err
+		}
+		if err != nil && rr.Error == "" {
+			rr.Error = err.Error()
+		}
+		rr.Duration = Duration(time.Since(started))
+		if head, herr := m.dir.RevParse(ctx, "HEAD"); herr == nil {
+			rr.MergeCommit = head
+		}
+		report.Refs = append(report.Refs, rr)
+		if err != nil {
+			return report, err
+		}
+
+		if rr.Outcome.Included() {
+			m.merged = append(m.merged, b)
+		}
+		st.Done = append(st.Done, rr)
+		st.Head = rr.MergeCommit
+		if err := m.saveState(ctx, st); err != nil {
+			return report, err
+		}
+	}
+	return report, m.removeState(ctx)
 }
 
-func (m *Merger) mergeBranch(b MergeRef, i, n int) error {
-	message := fmt.Sprintf("Experimental merge of %s", b.Name())
-	if err := m.dir.Command("git", "merge", "--no-ff", "--log", "-m", message, b.Sha()).Run(); err != nil {
-		return m.resolveConflict(b, message, 0, i, n)
+func mergeMessage(b MergeRef) string {
+	return fmt.Sprintf("Experimental merge of %s", b.Name())
+}
+
+func (m *Merger) mergeBranch(ctx context.Context, b MergeRef, rr *RefReport, i, n int) error {
+	message := mergeMessage(b)
+	if err := m.dir.Git().Merge(ctx, message, b.Sha()); err != nil {
+		if ctx.Err() != nil {
+			return m.abortCancelled(ctx, b)
+		}
+		conflict, cerr := m.inConflict(ctx)
+		if cerr != nil {
+			rr.Outcome = OutcomeFailed
+			return cerr
+		}
+		if !conflict {
+			// i.e. unknown revision, local changes overwritten by the merge
+			rr.Outcome = OutcomeFailed
+			slog.Error(fmt.Sprintf("Merge of %s failed", b.Name()), "error", err)
+			return fmt.Errorf("merging %s: %w", b.Name(), err)
+		}
+		return m.resolveConflict(ctx, b, rr, message, 0, i, n)
 	}
+	rr.Outcome = OutcomeMerged
 	return nil
 }
 
-func (m *Merger) resolveConflict(b MergeRef, message string, retry, i, n int) error {
+// inConflict returns true if the merge stopped on conflicts: there are unmerged paths or the merge is in progress
+func (m *Merger) inConflict(ctx context.Context) (bool, error) {
+	paths, err := m.dir.Git().UnmergedPaths(ctx)
+	if err != nil {
+		return false, fmt.Errorf("checking unmerged paths: %w", err)
+	}
+	if len(paths) > 0 {
+		return true, nil
+	}
+	inMerge, err := m.dir.Git().MergeInProgress(ctx)
+	if err != nil {
+		return false, fmt.Errorf("checking merge in progress: %w", err)
+	}
+	return inMerge, nil
+}
+
+// resolveConflict is not cancelled by the context (the conflict shell included),
+// Ctrl-C pressed in the shell stops the sequence after the ref is resolved
+func (m *Merger) resolveConflict(ctx context.Context, b MergeRef, rr *RefReport, message string, retry, i, n int) error {
+	ctx = context.WithoutCancel(ctx)
+	rr.Retries = retry
 	retries := m.ConflictRetries
 	if retries == 0 {
 		retries = 4
 	}
 	if retry > retries {
-		return fmt.Errorf(fmt.Sprintf("even after %d attempts the working dir is still not clean, aborting", retry))
+		rr.Outcome = OutcomeFailed
+		return fmt.Errorf("even after %d attempts the working dir is still not clean, aborting", retry)
+	}
+
+	// are there any unmerged files
+	unmerged, err := m.dir.Git().UnmergedPaths(ctx)
+	if err != nil {
+		rr.Outcome = OutcomeFailed
+		return fmt.Errorf("checking unmerged paths: %w", err)
 	}
+	if len(unmerged) > 0 {
+		if retry == 0 {
+			rr.ConflictPaths = unmerged
+			if m.Bisect {
+				m.findCulprits(ctx, b, rr)
+			}
+		}
 
-	hasunmerged := m.dir.Command("git", "diff", "--exit-code", "--quiet", "--diff-filter=U").Run() != nil
-	if hasunmerged {
-		// are there any unmerged files (--diff-filter=U)
-		output, _ := m.dir.Command("git", "diff", "--name-only", "--diff-filter=U").Output()
-		// lines := splitLines( output )
+		switch m.ConflictPolicy {
+		case ConflictSkip:
+			rr.Outcome = OutcomeSkipped
+			if err := m.abortMerge(ctx); err != nil {
+				return err
+			}
+			slog.Warn(fmt.Sprintf("Conflict in %s%s, skipping it", b.Name(), culpritsInfo(rr.Culprits)), "paths", rr.ConflictPaths)
+			return nil
+		case ConflictFail:
+			rr.Outcome = OutcomeFailed
+			if err := m.abortMerge(ctx); err != nil {
+				return err
+			}
+			return &ConflictError{Ref: b, Paths: rr.ConflictPaths, Culprits: rr.Culprits}
+		}
 
 		slog.Info(fmt.Sprintf(
-			`Conflict in %s, you have unmerged files:
+			`Conflict in %s%s, you have unmerged files:
 %s
-nResolve conflict, commit (or just add the files) and exit the shell (CTRL+D)`,
+Resolve conflict, commit (or just add the files) and exit the shell (CTRL+D)`,
 			b.Name(),
-			string(output),
+			culpritsInfo(rr.Culprits),
+			strings.Join(unmerged, "\n"),
 		))
 
 		prompt := m.conflictPrompt(b, retry, i, n)
 		if err := m.dir.RunBashWithPrompt(prompt); err != nil {
+			rr.Outcome = OutcomeFailed
 			return err
 		}
-		return m.resolveConflict(b, message, retry+1, i, n)
+		return m.resolveConflict(ctx, b, rr, message, retry+1, i, n)
 	} else {
 		// no conflict - do we have some staged files
 		// hascached := me.Command("git", "diff", "--cached", "--exit-code", "--quiet").Run() != nil
 
 		// are we inside merge ?
-		cmd := m.dir.Command("git", "rev-parse", "-q", "--verify", "MERGE_HEAD")
-		// &>/dev/null
-		cmd.Stderr = nil
+		inMerge, err := m.dir.Git().MergeInProgress(ctx)
+		if err != nil {
+			rr.Outcome = OutcomeFailed
+			return fmt.Errorf("checking merge in progress: %w", err)
+		}
 
-		if cmd.Run() == nil {
+		rr.Outcome = OutcomeManual
+		if inMerge {
 			newMessage := message
 			if retry == 0 {
+				rr.Outcome = OutcomeRerere
 				newMessage = newMessage + " with resolved conflict(s) using rerere"
 			}
-			return m.dir.Command("git", "commit", "-m", newMessage).Run()
+			if err := m.dir.Git().Commit(ctx, newMessage, false); err != nil {
+				rr.Outcome = OutcomeFailed
+				return err
+			}
+			return nil
+		}
+		if retry == 0 {
+			// merge failed without leaving any merge in progress
+			rr.Outcome = OutcomeFailed
+			return fmt.Errorf("merge of %s failed without any merge in progress", b.Name())
 		}
 		return nil
 	}
 }
 
+// order applies the orderer of the merger
+func (m *Merger) order(ctx context.Context, refs []MergeRef) ([]MergeRef, error) {
+	if m.Order == nil {
+		return refs, nil
+	}
+	ordered, err := m.Order.Order(ctx, refs)
+	if err != nil {
+		return nil, fmt.Errorf("ordering refs: %w", err)
+	}
+	return ordered, nil
+}
+
+// findCulprits fills the culprits of the conflict into the report, failure is not fatal
+func (m *Merger) findCulprits(ctx context.Context, b MergeRef, rr *RefReport) {
+	culprits, err := m.FindCulprits(ctx, m.base, m.merged, b)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Cannot find culprits of conflict in %s", b.Name()), "error", err)
+		return
+	}
+	rr.Culprits = refNames(culprits)
+}
+
+// culpritsInfo is a message suffix naming the culprits of the conflict
+func culpritsInfo(culprits []string) string {
+	if len(culprits) == 0 {
+		return ""
+	}
+	return " (conflicts with " + joinNames(culprits) + ")"
+}
+
+// abortMerge returns the working tree to the state before the merge
+func (m *Merger) abortMerge(ctx context.Context) error {
+	if err := m.dir.Git().MergeAbort(ctx); err != nil {
+		return fmt.Errorf("aborting merge: %w", err)
+	}
+	return nil
+}
+
+// abortCancelled aborts the merge interrupted by the cancellation of the context (if it was left in progress)
+func (m *Merger) abortCancelled(ctx context.Context, b MergeRef) error {
+	actx := context.WithoutCancel(ctx)
+	if inMerge, _ := m.dir.Git().MergeInProgress(actx); inMerge {
+		if err := m.abortMerge(actx); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("merge of %s interrupted, resume to continue: %w", b.Name(), ctx.Err())
+}
+
 // conflictPrompt is an informative bash prompt to be displayed on invoked bash
 func (m *Merger) conflictPrompt(b MergeRef, retry, i, n int) string {
 	name := b.Name()
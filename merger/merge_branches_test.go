@@ -0,0 +1,150 @@
+package merger
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/wayan/mergeexp/gitdir"
+	"github.com/wayan/mergeexp/gitdir/gitdirtest"
+)
+
+type testRef struct {
+	name, sha string
+}
+
+func (r testRef) Name() string { return r.name }
+func (r testRef) Sha() string  { return r.sha }
+
+// testRefs returns the refs named by the SHAs in upper case, i.e. "a" is A
+func testRefs(shas ...string) []MergeRef {
+	refs := make([]MergeRef, len(shas))
+	for i, sha := range shas {
+		refs[i] = testRef{name: string(sha[0] - 'a' + 'A'), sha: sha}
+	}
+	return refs
+}
+
+// conflictsWith returns the Conflict of gitdirtest.Backend: the ref conflicts (on f.txt)
+// once all its culprits are merged, the ref with no culprits conflicts with the base
+func conflictsWith(culprits map[string][]string) func(merged []string, sha string) []string {
+	return func(merged []string, sha string) []string {
+		refs, ok := culprits[sha]
+		if !ok {
+			return nil
+		}
+		for _, r := range refs {
+			if !slices.Contains(merged, r) {
+				return nil
+			}
+		}
+		return []string{"f.txt"}
+	}
+}
+
+func newTestMerger(t *testing.T, b *gitdirtest.Backend) *Merger {
+	t.Helper()
+	b.Dir = t.TempDir()
+	if b.Revs == nil {
+		b.Revs = map[string]string{"HEAD": "base", "base": "base"}
+	}
+	return New(&gitdir.Dir{Dir: b.Dir, Backend: b})
+}
+
+func outcomes(report *MergeReport) []Outcome {
+	var res []Outcome
+	for _, rr := range report.Refs {
+		res = append(res, rr.Outcome)
+	}
+	return res
+}
+
+func TestMergeBranchesSkip(t *testing.T) {
+	b := &gitdirtest.Backend{Conflict: conflictsWith(map[string][]string{"b": nil})}
+	m := newTestMerger(t, b)
+	m.ConflictPolicy = ConflictSkip
+
+	report, err := m.MergeBranches(context.Background(), testRefs("a", "b", "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := outcomes(report), []Outcome{OutcomeMerged, OutcomeSkipped, OutcomeMerged}; !slices.Equal(got, want) {
+		t.Errorf("outcomes = %v, want %v", got, want)
+	}
+	if got := report.Refs[1].ConflictPaths; !slices.Equal(got, []string{"f.txt"}) {
+		t.Errorf("conflict paths = %v", got)
+	}
+	if got := b.Merged("HEAD"); !slices.Equal(got, []string{"a", "c"}) {
+		t.Errorf("HEAD merged %v, want [a c]", got)
+	}
+	if !slices.Contains(b.Calls, "merge --abort") {
+		t.Errorf("conflicting merge not aborted: %v", b.Calls)
+	}
+	if _, err := m.LoadState(context.Background()); !errors.Is(err, ErrNoState) {
+		t.Errorf("state left after the finished sequence: %v", err)
+	}
+}
+
+func TestMergeBranchesFail(t *testing.T) {
+	b := &gitdirtest.Backend{Conflict: conflictsWith(map[string][]string{"b": {"a"}})}
+	m := newTestMerger(t, b)
+	m.ConflictPolicy = ConflictFail
+
+	report, err := m.MergeBranches(context.Background(), testRefs("a", "b", "c"))
+	var cerr *ConflictError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("error = %v, want *ConflictError", err)
+	}
+	if cerr.Ref.Name() != "B" || !slices.Equal(cerr.Paths, []string{"f.txt"}) {
+		t.Errorf("conflict of %s on %v", cerr.Ref.Name(), cerr.Paths)
+	}
+	if got, want := outcomes(report), []Outcome{OutcomeMerged, OutcomeFailed}; !slices.Equal(got, want) {
+		t.Errorf("outcomes = %v, want %v", got, want)
+	}
+	if inMerge, _ := b.MergeInProgress(context.Background()); inMerge {
+		t.Error("conflicting merge left in progress")
+	}
+}
+
+func TestMergeBranchesMergeError(t *testing.T) {
+	b := &gitdirtest.Backend{MergeErr: map[string]error{"b": errors.New("not something we can merge")}}
+	m := newTestMerger(t, b)
+	m.ConflictPolicy = ConflictSkip
+
+	report, err := m.MergeBranches(context.Background(), testRefs("a", "b", "c"))
+	if err == nil {
+		t.Fatal("failed merge not reported")
+	}
+	if got, want := outcomes(report), []Outcome{OutcomeMerged, OutcomeFailed}; !slices.Equal(got, want) {
+		t.Errorf("outcomes = %v, want %v", got, want)
+	}
+	if report.Refs[1].Error == "" {
+		t.Error("missing error in the report")
+	}
+	if slices.Contains(b.Calls, "merge --abort") {
+		t.Error("no merge to abort")
+	}
+}
+
+func TestRecordReport(t *testing.T) {
+	b := &gitdirtest.Backend{}
+	m := newTestMerger(t, b)
+	ctx := context.Background()
+
+	if prev, err := m.LoadReport(ctx, "HEAD"); err != nil || prev != nil {
+		t.Fatalf("LoadReport without note = %v, %v", prev, err)
+	}
+	report := &MergeReport{Base: "base", Refs: []RefReport{{Name: "A", SHA: "a", Outcome: OutcomeMerged, Duration: Duration(1500000)}}}
+	if err := m.RecordReport(ctx, "HEAD", report); err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.LoadReport(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, report) {
+		t.Errorf("LoadReport = %+v, want %+v", got, report)
+	}
+}
@@ -5,11 +5,23 @@ import "github.com/wayan/mergeexp/gitdir"
 type Merger struct {
 	dir             *gitdir.Dir
 	ConflictRetries int
+	ConflictPolicy  ConflictPolicy
+
+	// order of merging, refs are merged as given when nil
+	Order Orderer
+
+	// on conflict find the previously merged refs causing it (see FindCulprits)
+	Bisect bool
+
+	// state of the running MergeBranches
+	base   string
+	merged []MergeRef
 }
 
 func New(dir *gitdir.Dir) *Merger {
 	return &Merger{
 		dir:             dir,
 		ConflictRetries: 3,
+		ConflictPolicy:  ConflictInteractive,
 	}
 }
@@ -0,0 +1,204 @@
+package merger
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Orderer decides the order in which the refs are merged
+type Orderer interface {
+	Order(ctx context.Context, refs []MergeRef) ([]MergeRef, error)
+}
+
+// OrderFunc is a function implementing Orderer, it is not cancellable
+type OrderFunc func(refs []MergeRef) ([]MergeRef, error)
+
+func (f OrderFunc) Order(ctx context.Context, refs []MergeRef) ([]MergeRef, error) {
+	return f(refs)
+}
+
+// Optional interfaces of MergeRef used by the orderers,
+// refs not implementing them keep their relative order and are merged last
+
+// Identified is a ref with numeric ID (merge request ID)
+type Identified interface {
+	ID() int
+}
+
+// Timestamped is a ref with time of creation and of last update
+type Timestamped interface {
+	CreatedAt() time.Time
+	UpdatedAt() time.Time
+}
+
+// Prioritized is a ref with explicit priority, higher priority is merged first
+type Prioritized interface {
+	Priority() int
+}
+
+// Branched is a ref with known source and target branch
+type Branched interface {
+	SourceBranch() string
+	TargetBranch() string
+}
+
+// Dependent is a ref which must be merged after the refs (Identified) with the IDs
+type Dependent interface {
+	DependsOn() []int
+}
+
+var (
+	ByID = sortBy(func(r Identified) int { return r.ID() })
+
+	ByCreated = sortBy(func(r Timestamped) int64 { return r.CreatedAt().UnixNano() })
+
+	ByUpdated = sortBy(func(r Timestamped) int64 { return r.UpdatedAt().UnixNano() })
+
+	ByPriority = sortBy(func(r Prioritized) int { return -r.Priority() })
+
+	// ByDependency merges ref A before ref B when B targets the source branch of A
+	// or when B depends on A (Dependent)
+	ByDependency = OrderFunc(orderByDependency)
+)
+
+// NewOrderer returns the orderer of the name: "id", "created", "updated", "priority", "dependency"
+// or "conflicts" (ConflictOrder using the merger and the base); empty name keeps the order as it is
+func NewOrderer(name string, m *Merger, base string) (Orderer, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "id":
+		return ByID, nil
+	case "created":
+		return ByCreated, nil
+	case "updated":
+		return ByUpdated, nil
+	case "priority":
+		return ByPriority, nil
+	case "dependency":
+		return ByDependency, nil
+	case "conflicts":
+		return &ConflictOrder{Merger: m, Base: base}, nil
+	}
+	return nil, fmt.Errorf("unknown order '%s'", name)
+}
+
+// sortBy returns stable sorting orderer by the key of refs implementing I
+func sortBy[I any, K int | int64](key func(I) K) Orderer {
+	return OrderFunc(func(refs []MergeRef) ([]MergeRef, error) {
+		var keyed, rest []MergeRef
+		for _, r := range refs {
+			if _, ok := r.(I); ok {
+				keyed = append(keyed, r)
+			} else {
+				rest = append(rest, r)
+			}
+		}
+		slices.SortStableFunc(keyed, func(a, b MergeRef) int {
+			ka, kb := key(a.(I)), key(b.(I))
+			switch {
+			case ka < kb:
+				return -1
+			case ka > kb:
+				return 1
+			}
+			return 0
+		})
+		return append(keyed, rest...), nil
+	})
+}
+
+// orderByDependency is a stable topological sort, refs are dependent via branches or via IDs
+func orderByDependency(refs []MergeRef) ([]MergeRef, error) {
+	bySource := map[string]int{}
+	byID := map[int]int{}
+	for i, r := range refs {
+		if b, ok := r.(Branched); ok {
+			bySource[b.SourceBranch()] = i
+		}
+		if id, ok := r.(Identified); ok {
+			byID[id.ID()] = i
+		}
+	}
+
+	// dependencies of each ref
+	deps := make([][]int, len(refs))
+	for i, r := range refs {
+		if b, ok := r.(Branched); ok {
+			if j, ok := bySource[b.TargetBranch()]; ok && j != i {
+				deps[i] = append(deps[i], j)
+			}
+		}
+		if d, ok := r.(Dependent); ok {
+			// dependencies not among the refs are ignored
+			for _, id := range d.DependsOn() {
+				if j, ok := byID[id]; ok && j != i {
+					deps[i] = append(deps[i], j)
+				}
+			}
+		}
+	}
+
+	ordered := make([]MergeRef, 0, len(refs))
+	done := make([]bool, len(refs))
+	for len(ordered) < len(refs) {
+		progress := false
+		for i, r := range refs {
+			if !done[i] && !slices.ContainsFunc(deps[i], func(j int) bool { return !done[j] }) {
+				ordered = append(ordered, r)
+				done[i] = true
+				progress = true
+			}
+		}
+		if !progress {
+			var cyclic []MergeRef
+			for i, r := range refs {
+				if !done[i] {
+					cyclic = append(cyclic, r)
+				}
+			}
+			return nil, fmt.Errorf("cyclic dependency between %s", joinNames(refNames(cyclic)))
+		}
+	}
+	return ordered, nil
+}
+
+// ConflictOrder orders refs by the number of pairwise conflicts (see ConflictMatrix),
+// refs with fewer conflicts are merged first, refs conflicting with the base last
+type ConflictOrder struct {
+	Merger *Merger
+	Base   string
+}
+
+func (o *ConflictOrder) Order(ctx context.Context, refs []MergeRef) ([]MergeRef, error) {
+	cm, err := o.Merger.ConflictMatrix(ctx, o.Base, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	score := make([]int, len(refs))
+	indexes := make([]int, len(refs))
+	for i := range refs {
+		indexes[i] = i
+		if len(cm.Refs[i].BaseConflicts) > 0 {
+			score[i] = len(refs)
+			continue
+		}
+		for j := range refs {
+			if len(cm.Conflicts[i][j]) > 0 {
+				score[i]++
+			}
+		}
+	}
+
+	slices.SortStableFunc(indexes, func(a, b int) int {
+		return score[a] - score[b]
+	})
+	ordered := make([]MergeRef, 0, len(refs))
+	for _, i := range indexes {
+		ordered = append(ordered, refs[i])
+	}
+	return ordered, nil
+}
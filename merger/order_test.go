@@ -0,0 +1,178 @@
+package merger
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wayan/mergeexp/gitdir/gitdirtest"
+)
+
+// orderRef is a merge request implementing all the optional interfaces of the orderers
+type orderRef struct {
+	id       int
+	source   string
+	target   string
+	deps     []int
+	priority int
+	created  time.Time
+}
+
+func (r orderRef) Name() string         { return r.source }
+func (r orderRef) Sha() string          { return strings.ToLower(r.source) }
+func (r orderRef) ID() int              { return r.id }
+func (r orderRef) SourceBranch() string { return r.source }
+func (r orderRef) TargetBranch() string { return r.target }
+func (r orderRef) DependsOn() []int     { return r.deps }
+func (r orderRef) Priority() int        { return r.priority }
+func (r orderRef) CreatedAt() time.Time { return r.created }
+func (r orderRef) UpdatedAt() time.Time { return r.created }
+
+func TestOrderByDependency(t *testing.T) {
+	tests := []struct {
+		name    string
+		refs    []MergeRef
+		want    string
+		wantErr string
+	}{
+		{
+			name: "independent keep the order",
+			refs: []MergeRef{orderRef{id: 2, source: "B", target: "main"}, orderRef{id: 1, source: "A", target: "main"}},
+			want: "B A",
+		},
+		{
+			name: "stacked branches",
+			refs: []MergeRef{
+				orderRef{id: 1, source: "C", target: "B"},
+				orderRef{id: 2, source: "B", target: "A"},
+				orderRef{id: 3, source: "A", target: "main"},
+			},
+			want: "A B C",
+		},
+		{
+			name: "dependencies by id",
+			refs: []MergeRef{
+				orderRef{id: 1, source: "A", target: "main", deps: []int{3}},
+				orderRef{id: 2, source: "B", target: "main"},
+				orderRef{id: 3, source: "C", target: "main", deps: []int{2}},
+			},
+			want: "B C A",
+		},
+		{
+			name: "missing dependencies ignored",
+			refs: []MergeRef{
+				orderRef{id: 1, source: "A", target: "gone", deps: []int{42}},
+				orderRef{id: 2, source: "B", target: "main", deps: []int{1, 7}},
+			},
+			want: "A B",
+		},
+		{
+			name: "self dependency ignored",
+			refs: []MergeRef{orderRef{id: 1, source: "A", target: "A", deps: []int{1}}},
+			want: "A",
+		},
+		{
+			name: "plain refs",
+			refs: append(testRefs("x"), orderRef{id: 1, source: "A", target: "main"}),
+			want: "X A",
+		},
+		{
+			name: "cycle",
+			refs: []MergeRef{
+				orderRef{id: 1, source: "A", target: "main", deps: []int{3}},
+				orderRef{id: 2, source: "B", target: "main"},
+				orderRef{id: 3, source: "C", target: "A"},
+			},
+			wantErr: "cyclic dependency between A and C",
+		},
+		{
+			name: "cycle of branches",
+			refs: []MergeRef{
+				orderRef{id: 1, source: "A", target: "B"},
+				orderRef{id: 2, source: "B", target: "A"},
+			},
+			wantErr: "cyclic dependency between A and B",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ByDependency.Order(context.Background(), tt.refs)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := strings.Join(refNames(got), " "); names != tt.want {
+				t.Errorf("order = %s, want %s", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	refs := []MergeRef{
+		orderRef{id: 3, source: "A", priority: 1, created: day(2)},
+		testRef{"X", "x"},
+		orderRef{id: 1, source: "B", priority: 5, created: day(3)},
+		orderRef{id: 2, source: "C", priority: 1, created: day(1)},
+	}
+	tests := []struct {
+		order string
+		want  string
+	}{
+		{"id", "B C A X"},
+		{"created", "C A B X"},
+		{"priority", "B A C X"},
+		{"", "A X B C"},
+	}
+	for _, tt := range tests {
+		o, err := NewOrderer(tt.order, nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := refs
+		if o != nil {
+			if got, err = o.Order(context.Background(), refs); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if names := strings.Join(refNames(got), " "); names != tt.want {
+			t.Errorf("%s order = %s, want %s", tt.order, names, tt.want)
+		}
+	}
+	if _, err := NewOrderer("random", nil, ""); err == nil {
+		t.Error("unknown order accepted")
+	}
+}
+
+func TestConflictOrder(t *testing.T) {
+	// A conflicts with B and C, D with the base
+	b := &gitdirtest.Backend{Conflict: func(merged []string, sha string) []string {
+		pairs := []string{"a b", "b a", "a c", "c a"}
+		if sha == "d" {
+			return []string{"d.txt"}
+		}
+		for _, m := range merged {
+			if slices.Contains(pairs, m+" "+sha) {
+				return []string{"f.txt"}
+			}
+		}
+		return nil
+	}}
+	m := newTestMerger(t, b)
+
+	got, err := (&ConflictOrder{Merger: m, Base: "base"}).Order(context.Background(), testRefs("d", "a", "b", "c", "e"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(refNames(got), " "); names != "E B C A D" {
+		t.Errorf("order = %s, want E B C A D", names)
+	}
+}
@@ -0,0 +1,131 @@
+package merger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/wayan/mergeexp/gitdir"
+)
+
+// PlanStep is the predicted result of merging of a single MergeRef
+type PlanStep struct {
+	Name          string   `json:"name"`
+	SHA           string   `json:"sha"`
+	Clean         bool     `json:"clean"`
+	ConflictPaths []string `json:"conflict_paths,omitempty"`
+	// names of previously merged refs causing the conflict (Merger.Bisect)
+	Culprits []string `json:"culprits,omitempty"`
+	// tree of the merge, for conflicting merge it contains conflict markers
+	Tree string `json:"tree"`
+	// simulated merge commit, empty for conflicting merge
+	Commit string `json:"commit,omitempty"`
+}
+
+// Plan is the predicted result of MergeBranches
+type Plan struct {
+	Base  string     `json:"base"`
+	Steps []PlanStep `json:"steps"`
+}
+
+// Plan simulates merging of the refs (in the order of the merger) on top of base without touching the working tree.
+// A conflicting ref is treated as skipped, the following refs are merged without it.
+func (m *Merger) Plan(ctx context.Context, base string, refs []MergeRef) (*Plan, error) {
+	baseSha, err := m.dir.RevParse(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err = m.order(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Base: baseSha}
+	head := baseSha
+	var merged []MergeRef
+	for _, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, commit, err := m.simulateMerge(ctx, head, ref)
+		if err != nil {
+			return nil, err
+		}
+		step := PlanStep{
+			Name:          ref.Name(),
+			SHA:           ref.Sha(),
+			Clean:         result.Clean(),
+			ConflictPaths: result.Conflicts,
+			Tree:          result.Tree,
+			Commit:        commit,
+		}
+		if !step.Clean && m.Bisect {
+			culprits, err := m.FindCulprits(ctx, baseSha, merged, ref)
+			if err != nil {
+				return nil, err
+			}
+			step.Culprits = refNames(culprits)
+		}
+		plan.Steps = append(plan.Steps, step)
+		if commit != "" {
+			head = commit
+			merged = append(merged, ref)
+		}
+	}
+	return plan, nil
+}
+
+// Conflicting returns steps which are predicted to conflict
+func (p *Plan) Conflicting() []PlanStep {
+	var steps []PlanStep
+	for _, s := range p.Steps {
+		if !s.Clean {
+			steps = append(steps, s)
+		}
+	}
+	return steps
+}
+
+// WriteText writes the plan as a human readable table
+func (p *Plan) WriteText(w io.Writer) error {
+	for i, s := range p.Steps {
+		status := "clean"
+		if !s.Clean {
+			status = "CONFLICT " + strings.Join(s.ConflictPaths, ", ")
+			if len(s.Culprits) > 0 {
+				status += " with " + joinNames(s.Culprits)
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%3d %s %s: %s\n", i+1, shortSha(s.SHA), s.Name, status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simulateMerge merges ref into head in memory, for clean merge
+// the (dangling) merge commit is created so that the simulation can continue
+func (m *Merger) simulateMerge(ctx context.Context, head string, ref MergeRef) (*gitdir.MergeTreeResult, string, error) {
+	result, err := m.dir.MergeTree(ctx, head, ref.Sha())
+	if err != nil {
+		return nil, "", err
+	}
+	if !result.Clean() {
+		return result, "", nil
+	}
+
+	commit, err := m.dir.CommitTree(ctx, result.Tree, fmt.Sprintf("Simulated merge of %s", ref.Name()), head, ref.Sha())
+	if err != nil {
+		return nil, "", err
+	}
+	return result, commit, nil
+}
+
+func shortSha(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
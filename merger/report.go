@@ -0,0 +1,109 @@
+package merger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Outcome describes how a single MergeRef ended up in the merge sequence
+type Outcome string
+
+const (
+	// OutcomeMerged - merged without any conflict
+	OutcomeMerged Outcome = "merged"
+	// OutcomeRerere - conflicts were resolved by rerere
+	OutcomeRerere Outcome = "rerere"
+	// OutcomeManual - conflicts were resolved manually in the invoked shell
+	OutcomeManual Outcome = "manual"
+	// OutcomeSkipped - merge aborted due to conflict, ref is not included
+	OutcomeSkipped Outcome = "skipped"
+	// OutcomeFailed - merge failed, ref is not included
+	OutcomeFailed Outcome = "failed"
+)
+
+// Included returns true if the ref is part of the resulting branch
+func (o Outcome) Included() bool {
+	return o == OutcomeMerged || o == OutcomeRerere || o == OutcomeManual
+}
+
+// RefReport is the result of merging of a single MergeRef
+type RefReport struct {
+	Name    string  `json:"name"`
+	SHA     string  `json:"sha"`
+	Outcome Outcome `json:"outcome"`
+	// HEAD after the merge, for refs not included it is the unchanged HEAD
+	MergeCommit   string   `json:"merge_commit,omitempty"`
+	ConflictPaths []string `json:"conflict_paths,omitempty"`
+	// names of previously merged refs causing the conflict (Merger.Bisect)
+	Culprits []string `json:"culprits,omitempty"`
+	Retries  int      `json:"retries"`
+	Duration Duration `json:"duration"`
+	// reason of the failure (OutcomeFailed)
+	Error string `json:"error,omitempty"`
+}
+
+// Duration is time.Duration encoded in JSON as a string, i.e. "1.5s"
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts the string and the integer nanoseconds of the reports recorded before
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var ns int64
+	if err := json.Unmarshal(b, &ns); err == nil {
+		*d = Duration(ns)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MergeReport is the result of MergeBranches, one entry per MergeRef in the order of merging
+type MergeReport struct {
+	// the commit the refs were merged onto
+	Base string      `json:"base"`
+	Refs []RefReport `json:"refs"`
+}
+
+// Skipped returns reports of refs which are not part of the resulting branch
+func (r *MergeReport) Skipped() []RefReport {
+	var skipped []RefReport
+	for _, rr := range r.Refs {
+		if !rr.Outcome.Included() {
+			skipped = append(skipped, rr)
+		}
+	}
+	return skipped
+}
+
+// Summary returns human readable multiline summary suitable for a commit message
+func (r *MergeReport) Summary() string {
+	var sb strings.Builder
+	for _, rr := range r.Refs {
+		fmt.Fprintf(&sb, "%-8s %s (%s)", rr.Outcome, rr.Name, shortSha(rr.SHA))
+		if len(rr.ConflictPaths) > 0 {
+			fmt.Fprintf(&sb, " conflicts: %s", strings.Join(rr.ConflictPaths, ", "))
+		}
+		if len(rr.Culprits) > 0 {
+			fmt.Fprintf(&sb, " with %s", joinNames(rr.Culprits))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
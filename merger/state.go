@@ -0,0 +1,160 @@
+package merger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// StateFileName is the name of the file in the git dir where MergeBranches persists its progress
+const StateFileName = "mergeexp-state.json"
+
+// ErrNoState is returned by Resume when there is no interrupted merge sequence
+var ErrNoState = errors.New("no interrupted merge sequence")
+
+// State is the persisted progress of MergeBranches
+type State struct {
+	Base string     `json:"base"`
+	Refs []StateRef `json:"refs"`
+	// HEAD after the last processed ref
+	Head string `json:"head"`
+	// reports of the processed refs
+	Done []RefReport `json:"done"`
+}
+
+// StateRef is a persisted MergeRef
+type StateRef struct {
+	RefName string `json:"name"`
+	RefSha  string `json:"sha"`
+}
+
+func (r StateRef) Name() string { return r.RefName }
+func (r StateRef) Sha() string  { return r.RefSha }
+
+func newState(base string, refs []MergeRef) *State {
+	st := &State{Base: base, Head: base}
+	for _, r := range refs {
+		st.Refs = append(st.Refs, StateRef{RefName: r.Name(), RefSha: r.Sha()})
+	}
+	return st
+}
+
+func (m *Merger) statePath(ctx context.Context) (string, error) {
+	gitDir, err := m.dir.GitDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, StateFileName), nil
+}
+
+// LoadState reads the state of the interrupted merge sequence, returns ErrNoState if there is none
+func (m *Merger) LoadState(ctx context.Context) (*State, error) {
+	path, err := m.statePath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoState
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading state: %w", err)
+	}
+
+	var st State
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("parsing state %s: %w", path, err)
+	}
+	return &st, nil
+}
+
+func (m *Merger) saveState(ctx context.Context, st *State) error {
+	path, err := m.statePath(ctx)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+
+	// written atomically, the process may be killed anytime
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	return nil
+}
+
+func (m *Merger) removeState(ctx context.Context) error {
+	path, err := m.statePath(ctx)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing state: %w", err)
+	}
+	return nil
+}
+
+// Resume continues the merge sequence interrupted by the killed shell, reboot, etc.
+// HEAD must match the recorded state. A merge of the next ref left in progress
+// is resolved (same as after the conflict shell), a merge committed manually is accepted.
+func (m *Merger) Resume(ctx context.Context) (*MergeReport, error) {
+	st, err := m.LoadState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &MergeReport{Base: st.Base, Refs: st.Done}
+	if len(st.Done) >= len(st.Refs) {
+		return report, m.removeState(ctx)
+	}
+
+	head, err := m.dir.RevParse(ctx, "HEAD")
+	if err != nil {
+		return report, err
+	}
+
+	next := st.Refs[len(st.Done)]
+	// git reports full SHAs, the recorded one may be abbreviated (Bitbucket Cloud, commits pinned by directives)
+	nextSha, err := m.dir.RevParse(ctx, next.Sha()+"^{commit}")
+	if err != nil {
+		return report, fmt.Errorf("next ref %s: %w", next.Name(), err)
+	}
+	mergeHead, _ := m.dir.RevParse(ctx, "MERGE_HEAD")
+	switch {
+	case mergeHead != "":
+		if head != st.Head || mergeHead != nextSha {
+			return report, fmt.Errorf("merge in progress (%s into %s) does not match the recorded state (%s into %s)",
+				mergeHead, head, nextSha, st.Head)
+		}
+		return m.mergeFrom(ctx, st, true)
+
+	case head == st.Head:
+		return m.mergeFrom(ctx, st, false)
+	}
+
+	// the merge of the next ref may have been committed manually
+	first, _ := m.dir.RevParse(ctx, "HEAD^1")
+	second, _ := m.dir.RevParse(ctx, "HEAD^2")
+	if first != st.Head || second != nextSha {
+		return report, fmt.Errorf("HEAD %s does not match the recorded state %s", head, st.Head)
+	}
+	st.Done = append(st.Done, RefReport{
+		Name:        next.Name(),
+		SHA:         next.Sha(),
+		Outcome:     OutcomeManual,
+		MergeCommit: head,
+	})
+	st.Head = head
+	if err := m.saveState(ctx, st); err != nil {
+		return report, err
+	}
+	return m.mergeFrom(ctx, st, false)
+}
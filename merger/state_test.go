@@ -0,0 +1,134 @@
+package merger
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wayan/mergeexp/gitdir/gitdirtest"
+)
+
+func TestResume(t *testing.T) {
+	// P is pinned by the abbreviated SHA, git reports the full one
+	pinned := "abc1234" + strings.Repeat("0", 33)
+	tests := []struct {
+		name string
+		// the interruption after A is merged
+		interrupt func(b *gitdirtest.Backend)
+		want      []Outcome
+		wantErr   string
+	}{
+		{
+			name:      "killed between merges",
+			interrupt: func(b *gitdirtest.Backend) {},
+			want:      []Outcome{OutcomeMerged, OutcomeSkipped, OutcomeMerged},
+		},
+		{
+			name: "killed in conflict",
+			interrupt: func(b *gitdirtest.Backend) {
+				b.Merge(context.Background(), "", "abc1234")
+			},
+			want: []Outcome{OutcomeMerged, OutcomeSkipped, OutcomeMerged},
+		},
+		{
+			name: "merge committed manually",
+			interrupt: func(b *gitdirtest.Backend) {
+				b.Merge(context.Background(), "", "abc1234")
+				b.Commit(context.Background(), "resolved", false)
+			},
+			want: []Outcome{OutcomeMerged, OutcomeManual, OutcomeMerged},
+		},
+		{
+			name: "HEAD moved",
+			interrupt: func(b *gitdirtest.Backend) {
+				b.ResetHard(context.Background(), "base")
+			},
+			wantErr: "does not match the recorded state",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &gitdirtest.Backend{
+				Revs:     map[string]string{"HEAD": "base", "base": "base", "abc1234": pinned},
+				Conflict: conflictsWith(map[string][]string{pinned: nil}),
+			}
+			m := newTestMerger(t, b)
+			m.ConflictPolicy = ConflictSkip
+			ctx := context.Background()
+			refs := append(testRefs("a"), testRef{"P", "abc1234"}, testRef{"C", "c"})
+
+			// the state saved by MergeBranches after A
+			if err := b.Merge(ctx, "", "a"); err != nil {
+				t.Fatal(err)
+			}
+			head, _ := b.RevParse(ctx, "HEAD")
+			st := newState("base", refs)
+			st.Done = []RefReport{{Name: "A", SHA: "a", Outcome: OutcomeMerged, MergeCommit: head}}
+			st.Head = head
+			if err := m.saveState(ctx, st); err != nil {
+				t.Fatal(err)
+			}
+			tt.interrupt(b)
+
+			report, err := m.Resume(ctx)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr)
+				}
+				if _, err := m.LoadState(ctx); err != nil {
+					t.Errorf("state not kept for another resume: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := outcomes(report); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("outcomes = %v, want %v", got, tt.want)
+			}
+			if _, err := m.LoadState(ctx); !errors.Is(err, ErrNoState) {
+				t.Errorf("state left after resume: %v", err)
+			}
+		})
+	}
+}
+
+func TestResumeWithoutState(t *testing.T) {
+	m := newTestMerger(t, &gitdirtest.Backend{})
+	if _, err := m.Resume(context.Background()); !errors.Is(err, ErrNoState) {
+		t.Errorf("error = %v, want ErrNoState", err)
+	}
+}
+
+func TestMergeBranchesRemovesState(t *testing.T) {
+	b := &gitdirtest.Backend{MergeErr: map[string]error{"b": errors.New("unknown revision")}}
+	m := newTestMerger(t, b)
+	b.Revs["b"] = "b"
+	ctx := context.Background()
+
+	// failed sequence is resumable
+	if _, err := m.MergeBranches(ctx, testRefs("a", "b", "c")); err == nil {
+		t.Fatal("merge of B did not fail")
+	}
+	st, err := m.LoadState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Done) != 1 || st.Head != st.Done[0].MergeCommit {
+		t.Errorf("state = %+v, want A done", st)
+	}
+
+	delete(b.MergeErr, "b")
+	report, err := m.Resume(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := outcomes(report); !reflect.DeepEqual(got, []Outcome{OutcomeMerged, OutcomeMerged, OutcomeMerged}) {
+		t.Errorf("outcomes = %v", got)
+	}
+	if _, err := m.LoadState(ctx); !errors.Is(err, ErrNoState) {
+		t.Errorf("state left after resume: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+//go:build unix && !solaris
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// lockPollInterval is how often the lock held by another process is tried again
+const lockPollInterval = 200 * time.Millisecond
+
+// lock takes the exclusive lock of the mirror (flock of path.lock) waiting until
+// the other process releases it or the context is done
+func (c *Cache) lock(ctx context.Context, path string) (func(), error) {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating mirror dir: %w", err)
+	}
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("locking mirror: %w", err)
+	}
+
+	waiting := false
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if err != syscall.EWOULDBLOCK {
+			f.Close()
+			return nil, fmt.Errorf("locking mirror: %w", err)
+		}
+		if !waiting {
+			slog.Info(fmt.Sprintf("Waiting for the mirror %s used by another process", filepath.Base(path)))
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, fmt.Errorf("locking mirror: %w", ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
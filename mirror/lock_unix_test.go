@@ -0,0 +1,47 @@
+//go:build unix && !solaris
+
+package mirror
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	c := New(filepath.Join(t.TempDir(), "mirrors"))
+	path := c.Path("git@example.com:team/app.git")
+
+	unlock, err := c.lock(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// held by another process, flock locks are per open file
+	ctx, cancel := context.WithTimeout(context.Background(), 3*lockPollInterval)
+	defer cancel()
+	if _, err := c.lock(ctx, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second lock: %v, want the deadline exceeded", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		unlock, err := c.lock(context.Background(), path)
+		if err == nil {
+			unlock()
+		}
+		done <- err
+	}()
+	time.Sleep(lockPollInterval / 2)
+	unlock()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock not taken after the release")
+	}
+}
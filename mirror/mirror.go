@@ -0,0 +1,166 @@
+// Package mirror keeps bare mirrors of the upstream repositories in a cache directory.
+// Working repositories fetch from the mirror instead of the upstream, so the network
+// is used only to update the mirror and the objects are downloaded once
+// for all the working repositories.
+package mirror
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/wayan/mergeexp/gitdir"
+)
+
+// Cache is the directory of the mirrors, one per upstream url
+type Cache struct {
+	Dir string
+	// the working repositories borrow the objects of the mirrors (objects/info/alternates)
+	// instead of copying them. The working repository is broken when the mirror is deleted
+	// or when the mirror loses the objects it still refers to: the mirror prunes the refs
+	// removed or force pushed upstream, so the mirrors are kept from garbage collecting
+	// (gc.auto=0, gc.pruneExpire=never) and must not be gc'ed by hand either.
+	Alternates bool
+}
+
+func New(dir string) *Cache {
+	return &Cache{Dir: dir}
+}
+
+// DefaultDir returns the directory of the mirrors in the user cache directory
+func DefaultDir() (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locating cache dir: %w", err)
+	}
+	return filepath.Join(cache, "mergeexp", "mirrors"), nil
+}
+
+var unsafeChars = regexp.MustCompile(`[^-\w.]+`)
+
+// Path returns the path of the mirror of the url
+func (c *Cache) Path(url string) string {
+	name := strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.Dir, unsafeChars.ReplaceAllString(name, "-")+"-"+hex.EncodeToString(sum[:])[:12]+".git")
+}
+
+// Update creates (git clone --mirror) or updates (git fetch --prune) the mirror of the url
+// and returns its path. The git commands are run with the environment and the hook of wd.
+func (c *Cache) Update(ctx context.Context, wd *gitdir.Dir, url string) (string, error) {
+	path := c.Path(url)
+	unlock, err := c.lock(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	return path, c.update(ctx, wd, url, path)
+}
+
+// Fetch updates the mirror of the url and fetches the refspecs from the mirror into wd,
+// as git fetch --no-tags --prune url refspecs... would do
+func (c *Cache) Fetch(ctx context.Context, wd *gitdir.Dir, url string, refspecs ...string) error {
+	path := c.Path(url)
+	unlock, err := c.lock(ctx, path)
+	if err != nil {
+		return err
+	}
+	// the mirror is not updated in the middle of the fetch
+	defer unlock()
+
+	if err := c.update(ctx, wd, url, path); err != nil {
+		return err
+	}
+	if c.Alternates {
+		if err := keepObjects(ctx, wd, path); err != nil {
+			return err
+		}
+		if err := addAlternate(ctx, wd, filepath.Join(path, "objects")); err != nil {
+			return err
+		}
+	}
+	args := append([]string{"fetch", "--no-tags", "--prune", path}, refspecs...)
+	if _, err := wd.Run(ctx, args...); err != nil {
+		return fmt.Errorf("fetching from mirror of %s: %w", url, err)
+	}
+	return nil
+}
+
+func (c *Cache) update(ctx context.Context, wd *gitdir.Dir, url, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		md := &gitdir.Dir{Dir: path, Env: wd.Env, Hook: wd.Hook}
+		if _, err := md.Run(ctx, "fetch", "--prune"); err != nil {
+			return fmt.Errorf("updating mirror of %s: %w", url, err)
+		}
+		return nil
+	}
+
+	slog.Info(fmt.Sprintf("Creating mirror of %s in %s", url, path))
+	// cloned aside, an interrupted clone is not mistaken for the mirror
+	tmp := path + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return fmt.Errorf("removing %s: %w", tmp, err)
+	}
+	cd := &gitdir.Dir{Dir: c.Dir, Env: wd.Env, Hook: wd.Hook}
+	if _, err := cd.Run(ctx, "clone", "--mirror", url, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("creating mirror of %s: %w", url, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("creating mirror of %s: %w", url, err)
+	}
+	return nil
+}
+
+// keepObjects disables the garbage collection of the mirror, the objects unreachable
+// from the mirror refs may still be used by the working repositories borrowing them
+func keepObjects(ctx context.Context, wd *gitdir.Dir, path string) error {
+	md := &gitdir.Dir{Dir: path, Env: wd.Env, Hook: wd.Hook}
+	for _, kv := range [][2]string{{"gc.auto", "0"}, {"gc.pruneExpire", "never"}} {
+		if _, err := md.Run(ctx, "config", kv[0], kv[1]); err != nil {
+			return fmt.Errorf("configuring mirror %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// addAlternate adds the objects directory to objects/info/alternates of wd unless it is there
+func addAlternate(ctx context.Context, wd *gitdir.Dir, objects string) error {
+	common, err := wd.Output(ctx, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return fmt.Errorf("locating git dir: %w", err)
+	}
+	file := filepath.Join(common, "objects", "info", "alternates")
+
+	b, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading alternates: %w", err)
+	}
+	if slices.Contains(strings.Split(string(b), "\n"), objects) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("writing alternates: %w", err)
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("writing alternates: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(objects + "\n"); err != nil {
+		return fmt.Errorf("writing alternates: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package mirror
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wayan/mergeexp/gitdir"
+	"github.com/wayan/mergeexp/gitdir/gitdirtest"
+)
+
+func TestPath(t *testing.T) {
+	c := New("/cache")
+	tests := []struct {
+		url, prefix string
+	}{
+		{"git@gitlab.example.com:team/app.git", "/cache/app-"},
+		{"https://github.com/team/app/", "/cache/app-"},
+		{"ssh://git@bitbucket.example.com:7999/prj/my app.git", "/cache/my-app-"},
+	}
+	paths := map[string]bool{}
+	for _, tt := range tests {
+		path := c.Path(tt.url)
+		if !strings.HasPrefix(path, tt.prefix) || !strings.HasSuffix(path, ".git") {
+			t.Errorf("Path(%s) = %s, want %s<hash>.git", tt.url, path, tt.prefix)
+		}
+		paths[path] = true
+	}
+	if len(paths) != len(tests) {
+		t.Errorf("paths not unique: %v", paths)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	origin := gitdirtest.Repo(t)
+	first := gitdirtest.Commit(t, origin, "a.txt", "1")
+	c := New(t.TempDir())
+	wd := gitdirtest.Repo(t)
+	ctx := context.Background()
+
+	fetch := func() string {
+		t.Helper()
+		if err := c.Fetch(ctx, wd, origin.Dir, "+refs/heads/main:refs/mergeexp/main"); err != nil {
+			t.Fatal(err)
+		}
+		return gitdirtest.Git(t, wd, "rev-parse", "refs/mergeexp/main")
+	}
+	if got := fetch(); got != first {
+		t.Errorf("fetched %s, want %s", got, first)
+	}
+	if _, err := os.Stat(c.Path(origin.Dir)); err != nil {
+		t.Errorf("mirror not created: %v", err)
+	}
+
+	// the mirror is updated
+	second := gitdirtest.Commit(t, origin, "a.txt", "2")
+	if got := fetch(); got != second {
+		t.Errorf("fetched %s, want %s", got, second)
+	}
+	if _, err := os.Stat(filepath.Join(wd.Dir, ".git", "objects", "info", "alternates")); !os.IsNotExist(err) {
+		t.Errorf("alternates without Alternates: %v", err)
+	}
+}
+
+func TestFetchAlternates(t *testing.T) {
+	origin := gitdirtest.Repo(t)
+	head := gitdirtest.Commit(t, origin, "a.txt", "1")
+	c := &Cache{Dir: t.TempDir(), Alternates: true}
+	wd := gitdirtest.Repo(t)
+	ctx := context.Background()
+
+	for range 2 {
+		if err := c.Fetch(ctx, wd, origin.Dir, "+refs/heads/main:refs/mergeexp/main"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(wd.Dir, ".git", "objects", "info", "alternates"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), filepath.Join(c.Path(origin.Dir), "objects")+"\n"; got != want {
+		t.Errorf("alternates = %q, want %q added once", got, want)
+	}
+	if got := gitdirtest.Git(t, wd, "rev-parse", "refs/mergeexp/main"); got != head {
+		t.Errorf("fetched %s, want %s", got, head)
+	}
+
+	// the borrowed objects are kept by the mirror
+	md := &gitdir.Dir{Dir: c.Path(origin.Dir), Env: wd.Env}
+	for key, want := range map[string]string{"gc.auto": "0", "gc.pruneExpire": "never"} {
+		if got := gitdirtest.Git(t, md, "config", key); got != want {
+			t.Errorf("mirror %s = %s, want %s", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FeedbackText returns the markdown text of the comment (note) posting the feedback,
+// the marker is the hidden first line the comment is found by on rebuilds
+func FeedbackText(marker string, fb Feedback) string {
+	var sb strings.Builder
+	sb.WriteString(marker + "\n\n")
+
+	rr := fb.Result
+	if rr.Outcome.Included() {
+		fmt.Fprintf(&sb, "Included in experimental build `%s` at commit %s", fb.Branch, fb.Commit)
+		if rr.SHA != "" {
+			fmt.Fprintf(&sb, " (merged head %s, %s)", rr.SHA, rr.Outcome)
+		}
+		sb.WriteString(".\n")
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "Not included in experimental build `%s` at commit %s (%s)", fb.Branch, fb.Commit, rr.Outcome)
+	if len(rr.ConflictPaths) == 0 {
+		sb.WriteString(".\n")
+		return sb.String()
+	}
+	sb.WriteString(" because of conflicts on paths:\n\n")
+	for _, path := range rr.ConflictPaths {
+		fmt.Fprintf(&sb, "- `%s`\n", path)
+	}
+	if len(rr.Culprits) > 0 {
+		sb.WriteString("\nConflicting with:\n\n")
+		for _, culprit := range rr.Culprits {
+			fmt.Fprintf(&sb, "- %s\n", culprit)
+		}
+	}
+	return sb.String()
+}
+
+// CommentMarker is the marker (HTML comment) of the feedback of the experimental branch
+// for the forges rendering the comments as markdown with HTML
+func CommentMarker(branch string) string {
+	return fmt.Sprintf("<!-- mergeexp:%s -->", branch)
+}
+
+// PostFeedback posts FeedbackText as a comment, the comment is idempotent across rebuilds:
+// the first of the existing comments (bodies from the oldest) containing the marker is edited
+// unless it is unchanged, a new comment is created only if there is none.
+// The edit gets the index of the comment in bodies.
+func PostFeedback(bodies []string, marker string, fb Feedback, create func(body string) error, edit func(i int, body string) error) error {
+	body := FeedbackText(marker, fb)
+	for i, b := range bodies {
+		if !strings.Contains(b, marker) {
+			continue
+		}
+		if b == body {
+			return nil
+		}
+		return edit(i, body)
+	}
+	return create(body)
+}
+
+// State of the change request in the build, the forges map it onto their commit (build) statuses
+type State int
+
+const (
+	// StateIncluded - merged into the experimental branch
+	StateIncluded State = iota
+	// StateConflict - not included because of conflicts
+	StateConflict
+	// StateExcluded - not included for other reason, i.e. failed merge
+	StateExcluded
+)
+
+// FeedbackState returns the state of the change request in the build and its short description
+func FeedbackState(fb Feedback) (State, string) {
+	switch rr := fb.Result; {
+	case rr.Outcome.Included():
+		return StateIncluded, "in experimental build at " + fb.Commit
+	case len(rr.ConflictPaths) > 0:
+		return StateConflict, "conflict: " + strings.Join(rr.ConflictPaths, ", ")
+	default:
+		return StateExcluded, fmt.Sprintf("excluded (%s)", rr.Outcome)
+	}
+}
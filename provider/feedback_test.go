@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/wayan/mergeexp/merger"
+)
+
+func TestFeedbackText(t *testing.T) {
+	tests := []struct {
+		result merger.RefReport
+		want   string
+	}{
+		{
+			merger.RefReport{SHA: "abc", Outcome: merger.OutcomeRerere},
+			"Included in experimental build `exp` at commit c1 (merged head abc, rerere).\n",
+		},
+		{
+			merger.RefReport{Outcome: merger.OutcomeFailed},
+			"Not included in experimental build `exp` at commit c1 (failed).\n",
+		},
+		{
+			merger.RefReport{Outcome: merger.OutcomeSkipped, ConflictPaths: []string{"a.txt"}, Culprits: []string{"PR 2: other"}},
+			"Not included in experimental build `exp` at commit c1 (skipped) because of conflicts on paths:\n\n" +
+				"- `a.txt`\n\nConflicting with:\n\n- PR 2: other\n",
+		},
+	}
+	for _, tt := range tests {
+		got := FeedbackText("<!-- m -->", Feedback{Branch: "exp", Commit: "c1", Result: tt.result})
+		if want := "<!-- m -->\n\n" + tt.want; got != want {
+			t.Errorf("FeedbackText(%+v) = %q, want %q", tt.result, got, want)
+		}
+	}
+}
+
+func TestPostFeedback(t *testing.T) {
+	fb := Feedback{Branch: "exp", Commit: "c1", Result: merger.RefReport{Outcome: merger.OutcomeMerged}}
+	marker := CommentMarker("exp")
+	body := FeedbackText(marker, fb)
+	tests := []struct {
+		name   string
+		bodies []string
+		want   string
+	}{
+		{"created", []string{"LGTM", CommentMarker("other") + "\n\nfeedback of other branch"}, "create"},
+		{"edited", []string{"LGTM", marker + "\n\nold feedback", marker + "\n\nduplicate"}, "edit 1"},
+		{"unchanged", []string{body}, ""},
+		{"no comments", nil, "create"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			err := PostFeedback(tt.bodies, marker, fb,
+				func(b string) error {
+					if b != body {
+						t.Errorf("created %q", b)
+					}
+					calls = append(calls, "create")
+					return nil
+				},
+				func(i int, b string) error {
+					if b != body {
+						t.Errorf("edited to %q", b)
+					}
+					calls = append(calls, fmt.Sprint("edit ", i))
+					return nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(calls, ", "); got != tt.want {
+				t.Errorf("calls %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedbackState(t *testing.T) {
+	tests := []struct {
+		result      merger.RefReport
+		state       State
+		description string
+	}{
+		{merger.RefReport{Outcome: merger.OutcomeManual}, StateIncluded, "in experimental build at c1"},
+		{merger.RefReport{Outcome: merger.OutcomeSkipped, ConflictPaths: []string{"a", "b"}}, StateConflict, "conflict: a, b"},
+		{merger.RefReport{Outcome: merger.OutcomeFailed}, StateExcluded, "excluded (failed)"},
+	}
+	for _, tt := range tests {
+		state, description := FeedbackState(Feedback{Branch: "exp", Commit: "c1", Result: tt.result})
+		if state != tt.state || description != tt.description {
+			t.Errorf("FeedbackState(%+v) = %d %q, want %d %q", tt.result, state, description, tt.state, tt.description)
+		}
+	}
+}
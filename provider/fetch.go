@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/wayan/mergeexp/gitdir"
+)
+
+// Head is the head of a change request fetched from the ref of the forge repository into a local ref
+type Head struct {
+	// name of the change request
+	Name string
+	// the ref in the fetched repository, i.e. refs/pull/7/head
+	Remote string
+	// the local ref, i.e. refs/mergeexp/pull/7
+	Local string
+	// the head the change request was listed with, replaced by the fetched one
+	Sha string
+}
+
+// FetchHeads force-fetches the heads from the repository at url into their local refs, no remotes are added.
+// The change requests whose head moved since listing (i.e. force-pushed) are reported and get the fetched head,
+// as the listed one may not be fetched at all.
+func FetchHeads(ctx context.Context, dir *gitdir.Dir, url string, heads []Head) error {
+	if len(heads) == 0 {
+		return nil
+	}
+	args := []string{"fetch", "--no-tags", "--no-write-fetch-head", url}
+	for _, h := range heads {
+		args = append(args, "+"+h.Remote+":"+h.Local)
+	}
+	if _, err := dir.Run(ctx, args...); err != nil {
+		return fmt.Errorf("fetching change requests from %s: %w", url, err)
+	}
+
+	for i, h := range heads {
+		head, err := dir.RevParse(ctx, h.Local)
+		if err != nil {
+			return fmt.Errorf("head of %s: %w", h.Name, err)
+		}
+		if head != h.Sha {
+			slog.Warn(fmt.Sprintf("%s moved since listing, merging the fetched head", h.Name), "listed", h.Sha, "head", head)
+			heads[i].Sha = head
+		}
+	}
+	return nil
+}
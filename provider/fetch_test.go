@@ -0,0 +1,39 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/wayan/mergeexp/gitdir/gitdirtest"
+)
+
+func TestFetchHeads(t *testing.T) {
+	origin := gitdirtest.Repo(t)
+	listed := gitdirtest.Commit(t, origin, "a.txt", "1")
+	gitdirtest.Git(t, origin, "update-ref", "refs/pull/1/head", listed)
+	gitdirtest.Git(t, origin, "update-ref", "refs/pull/2/head", listed)
+	// force-pushed after listing
+	moved := gitdirtest.Commit(t, origin, "a.txt", "2")
+	gitdirtest.Git(t, origin, "update-ref", "refs/pull/2/head", moved)
+
+	dir := gitdirtest.Repo(t)
+	heads := []Head{
+		{Name: "PR 1", Remote: "refs/pull/1/head", Local: "refs/mergeexp/pull/1", Sha: listed},
+		{Name: "PR 2", Remote: "refs/pull/2/head", Local: "refs/mergeexp/pull/2", Sha: listed},
+	}
+	if err := FetchHeads(context.Background(), dir, origin.Dir, heads); err != nil {
+		t.Fatal(err)
+	}
+	if heads[0].Sha != listed || heads[1].Sha != moved {
+		t.Errorf("heads = %+v, want %s and the moved %s", heads, listed, moved)
+	}
+	if got := gitdirtest.Git(t, dir, "rev-parse", "refs/mergeexp/pull/2"); got != moved {
+		t.Errorf("local ref at %s, want %s", got, moved)
+	}
+
+	err := FetchHeads(context.Background(), dir, origin.Dir, []Head{{Name: "PR 3", Remote: "refs/pull/3/head", Local: "refs/mergeexp/pull/3"}})
+	if err == nil || !strings.Contains(err.Error(), "refs/pull/3/head") {
+		t.Errorf("fetch of missing ref: %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/wayan/mergeexp/gitdir"
+	"github.com/wayan/mergeexp/selection"
+)
+
+// CheckPinned drops the commit pinned by the directive (tag@sha) unless it is in the history
+// of the fetched head of the change request, otherwise anyone able to comment could get
+// an arbitrary commit merged. The head is merged then.
+func CheckPinned(ctx context.Context, dir *gitdir.Dir, name string, d *selection.Directive, head string) {
+	if d == nil || d.Sha == "" {
+		return
+	}
+	ok, err := dir.IsAncestor(ctx, d.Sha, head)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Cannot check commit %s pinned by the directive of %s, merging the head", d.Sha, name), "error", err)
+		d.Sha = ""
+	} else if !ok {
+		slog.Warn(fmt.Sprintf("Commit %s pinned by the directive is not in the source branch of %s, merging the head", d.Sha, name))
+		d.Sha = ""
+	}
+}
@@ -0,0 +1,71 @@
+// Package provider defines the common interface of the forges (GitLab, Bitbucket, GitHub, ...)
+// providing the change requests (merge requests, pull requests) to be merged.
+// The implementations live in the packages of the forges.
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/wayan/mergeexp/gitdir"
+	"github.com/wayan/mergeexp/merger"
+)
+
+// ErrNotSupported is returned by the operations the provider does not implement
+var ErrNotSupported = errors.New("not supported by the provider")
+
+// Filter selects the candidate change requests
+type Filter struct {
+	// target branches, all if empty
+	TargetBranches []string
+	// labels the change request must have, or
+	Labels []string
+	// deployment tags from comments ("deployment: tag"), see selection.Policy
+	Tags []string
+}
+
+// Feedback is the result of the build posted back to the change request
+type Feedback struct {
+	// experimental branch
+	Branch string
+	// the final commit of the build
+	Commit string
+	// result of the change request in the build
+	Result merger.RefReport
+}
+
+// Provider lists and fetches the change requests of a single target repository.
+// The refs passed to CloneURL, Fetch and Feedback must come from ChangeRequests of the same provider.
+type Provider interface {
+	// ChangeRequests returns the open change requests matching the filter
+	ChangeRequests(ctx context.Context, f Filter) ([]merger.MergeRef, error)
+	// CloneURL returns the clone URL of the repository with the source of the change request
+	CloneURL(ctx context.Context, ref merger.MergeRef) (string, error)
+	// Fetch makes the heads (Sha) of the change requests present in the local repository
+	Fetch(ctx context.Context, dir *gitdir.Dir, refs []merger.MergeRef) error
+	// Feedback posts the result of the build to the change request
+	Feedback(ctx context.Context, ref merger.MergeRef, fb Feedback) error
+}
+
+// ErrForeignRef is returned when the ref does not come from the provider
+var ErrForeignRef = errors.New("merge ref does not come from the provider")
+
+// CheckFetched returns error naming the refs whose heads are not present in the local repository
+func CheckFetched(ctx context.Context, dir *gitdir.Dir, refs []merger.MergeRef) error {
+	var errs []error
+	for _, ref := range refs {
+		if !dir.ShaExists(ctx, ref.Sha()) {
+			errs = append(errs, &NotFetchedError{Ref: ref})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotFetchedError - the head of the ref is not in the local repository after fetch
+type NotFetchedError struct {
+	Ref merger.MergeRef
+}
+
+func (e *NotFetchedError) Error() string {
+	return "head " + e.Ref.Sha() + " of " + e.Ref.Name() + " not fetched"
+}
@@ -0,0 +1,180 @@
+package selection
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DirectivePrefix starts the deployment directive in a comment line
+const DirectivePrefix = "deployment:"
+
+// Directive is the parsed deployment directive, the grammar is
+//
+//	deployment: [no-]tag[@sha] [until YYYY-MM-DD] [priority=N] [after !N]... [free text]
+//
+// i.e. "deployment: exp until 2026-11-30 priority=10 after !123".
+// The first unknown word starts the free text, i.e. "deployment: exp please",
+// an unknown key=value word is an error (i.e. misspelled priority).
+type Directive struct {
+	Tag string
+	// "deployment: no-tag" excludes the change request
+	Exclude bool
+	// last day the directive is valid, zero if unlimited
+	Until time.Time
+	// merge priority, higher is merged first
+	Priority    int
+	HasPriority bool
+	// IDs (!N or #N) of the change requests to be merged before
+	After []int
+	// the commit to be merged instead of the head,
+	// the providers accept only a commit of the source branch
+	Sha string
+}
+
+// Expired returns true if the directive is not valid at the time
+func (d *Directive) Expired(now time.Time) bool {
+	return !d.Until.IsZero() && !now.Before(d.Until.AddDate(0, 0, 1))
+}
+
+// Deployed returns true if the directive includes the change request at the time
+func (d *Directive) Deployed(now time.Time) bool {
+	return !d.Exclude && !d.Expired(now)
+}
+
+// DirectiveError describes the malformed directive
+type DirectiveError struct {
+	// line of the comment, from 1
+	Line int
+	Text string
+	Msg  string
+}
+
+func (e *DirectiveError) Error() string {
+	return fmt.Sprintf("line %d: malformed directive '%s': %s", e.Line, e.Text, e.Msg)
+}
+
+var (
+	directiveRe = regexp.MustCompile(`\b` + DirectivePrefix)
+	tagRe       = regexp.MustCompile(`^[-\w.:/]+$`)
+	shaRe       = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+	keyValueRe  = regexp.MustCompile(`^\w[-\w]*=`)
+)
+
+// ParseDirectives returns the directives of the comment in the order of lines, lines without directive are ignored.
+// The malformed directives are skipped and reported as joined DirectiveErrors.
+func ParseDirectives(comment string) ([]*Directive, error) {
+	var directives []*Directive
+	var errs []error
+	for i, line := range strings.Split(comment, "\n") {
+		loc := directiveRe.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		d, err := ParseDirective(line[loc[1]:])
+		if err != nil {
+			errs = append(errs, &DirectiveError{Line: i + 1, Text: strings.TrimSpace(line[loc[0]:]), Msg: err.Error()})
+			continue
+		}
+		directives = append(directives, d)
+	}
+	return directives, errors.Join(errs...)
+}
+
+// ParseDirective parses the text following DirectivePrefix
+func ParseDirective(s string) (*Directive, error) {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return nil, errors.New("missing tag")
+	}
+
+	d := &Directive{}
+	tag := words[0]
+	if rest, ok := strings.CutPrefix(tag, "no-"); ok {
+		d.Exclude = true
+		tag = rest
+	}
+	if t, sha, ok := strings.Cut(tag, "@"); ok {
+		if d.Exclude {
+			return nil, errors.New("excluding directive cannot pin a commit")
+		}
+		if !shaRe.MatchString(sha) {
+			return nil, fmt.Errorf("invalid commit '%s'", sha)
+		}
+		tag, d.Sha = t, strings.ToLower(sha)
+	}
+	if !tagRe.MatchString(tag) {
+		return nil, fmt.Errorf("invalid tag '%s'", tag)
+	}
+	d.Tag = tag
+
+	for i := 1; i < len(words); i++ {
+		word := words[i]
+		switch {
+		case word == "until":
+			if i++; i == len(words) {
+				return nil, errors.New("missing date after 'until'")
+			}
+			until, err := time.ParseInLocation(time.DateOnly, words[i], time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("invalid date '%s', YYYY-MM-DD expected", words[i])
+			}
+			d.Until = until
+		case word == "after":
+			if i++; i == len(words) {
+				return nil, errors.New("missing change request after 'after'")
+			}
+			id, err := parseID(words[i])
+			if err != nil {
+				return nil, err
+			}
+			d.After = append(d.After, id)
+		case strings.HasPrefix(word, "priority="):
+			p, err := strconv.Atoi(strings.TrimPrefix(word, "priority="))
+			if err != nil {
+				return nil, fmt.Errorf("invalid priority '%s'", word)
+			}
+			d.Priority, d.HasPriority = p, true
+		case keyValueRe.MatchString(word):
+			return nil, fmt.Errorf("unknown key '%s'", word)
+		default:
+			// free text follows
+			return d, nil
+		}
+	}
+	return d, nil
+}
+
+// parseID parses the reference of change request: !123 (GitLab) or #123 (Bitbucket, Gitea, GitHub)
+func parseID(s string) (int, error) {
+	if len(s) > 1 && (s[0] == '!' || s[0] == '#') {
+		if id, err := strconv.Atoi(s[1:]); err == nil && id > 0 {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid change request '%s', !N or #N expected", s)
+}
+
+// LatestDirective returns the directive for any of the tags from the last comment having one,
+// comments are expected from the oldest. Malformed directives are skipped and reported as the error.
+func LatestDirective(comments []string, tags []string) (*Directive, error) {
+	var latest *Directive
+	var errs []error
+	for i, comment := range comments {
+		directives, err := ParseDirectives(comment)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("comment %d: %w", i+1, err))
+		}
+		for _, d := range directives {
+			if slices.Contains(tags, d.Tag) {
+				latest = d
+				break
+			}
+		}
+	}
+	return latest, errors.Join(errs...)
+}
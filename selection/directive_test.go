@@ -0,0 +1,134 @@
+package selection
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDirective(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		in      string
+		want    *Directive
+		wantErr string
+	}{
+		{in: "exp", want: &Directive{Tag: "exp"}},
+		{in: "  release/2.5  ", want: &Directive{Tag: "release/2.5"}},
+		{in: "no-exp", want: &Directive{Tag: "exp", Exclude: true}},
+		{in: "exp@ABCDEF1", want: &Directive{Tag: "exp", Sha: "abcdef1"}},
+		{
+			in:   "exp until 2026-11-30 priority=-5 after !12 after #7",
+			want: &Directive{Tag: "exp", Until: date("2026-11-30"), Priority: -5, HasPriority: true, After: []int{12, 7}},
+		},
+		{in: "exp priority=0", want: &Directive{Tag: "exp", HasPriority: true}},
+		{in: "exp please, thanks", want: &Directive{Tag: "exp"}},
+		{in: "exp needs FOO=1 set", want: &Directive{Tag: "exp"}},
+		{in: "exp priority=3 see !5 after !6", want: &Directive{Tag: "exp", Priority: 3, HasPriority: true}},
+		{in: "", wantErr: "missing tag"},
+		{in: "exp!", wantErr: "invalid tag 'exp!'"},
+		{in: "exp@xyz", wantErr: "invalid commit 'xyz'"},
+		{in: "exp@abc", wantErr: "invalid commit 'abc'"},
+		{in: "no-exp@abcdef1", wantErr: "excluding directive cannot pin a commit"},
+		{in: "exp until", wantErr: "missing date after 'until'"},
+		{in: "exp until 30.11.2026", wantErr: "invalid date '30.11.2026', YYYY-MM-DD expected"},
+		{in: "exp after", wantErr: "missing change request after 'after'"},
+		{in: "exp after 12", wantErr: "invalid change request '12', !N or #N expected"},
+		{in: "exp after !0", wantErr: "invalid change request '!0', !N or #N expected"},
+		{in: "exp priority=high", wantErr: "invalid priority 'priority=high'"},
+		{in: "exp priorty=10", wantErr: "unknown key 'priorty=10'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDirective(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDirective = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDirectives(t *testing.T) {
+	comment := strings.Join([]string{
+		"Please deploy",
+		"deployment: exp priority=2",
+		"redeployment: nothing",
+		"> deployment: exp after 12",
+		"deployment: no-staging",
+	}, "\n")
+
+	directives, err := ParseDirectives(comment)
+	want := []*Directive{
+		{Tag: "exp", Priority: 2, HasPriority: true},
+		{Tag: "staging", Exclude: true},
+	}
+	if !reflect.DeepEqual(directives, want) {
+		t.Errorf("directives = %+v, want %+v", directives, want)
+	}
+	if err == nil || err.Error() != "line 4: malformed directive 'deployment: exp after 12': invalid change request '12', !N or #N expected" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestLatestDirective(t *testing.T) {
+	comments := []string{
+		"deployment: exp",
+		"deployment: staging priority=1",
+		"deployment: no-exp\ndeployment: staging",
+		"deployment: other",
+	}
+	tests := []struct {
+		tags []string
+		want *Directive
+	}{
+		{[]string{"exp"}, &Directive{Tag: "exp", Exclude: true}},
+		{[]string{"staging"}, &Directive{Tag: "staging"}},
+		// the first directive of the comment wins
+		{[]string{"exp", "staging"}, &Directive{Tag: "exp", Exclude: true}},
+		{[]string{"prod"}, nil},
+	}
+	for _, tt := range tests {
+		got, err := LatestDirective(comments, tt.tags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LatestDirective(%v) = %+v, want %+v", tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestDirectiveDeployed(t *testing.T) {
+	until := time.Date(2026, 11, 30, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		d    Directive
+		now  time.Time
+		want bool
+	}{
+		{Directive{Tag: "exp"}, until, true},
+		{Directive{Tag: "exp", Exclude: true}, until, false},
+		{Directive{Tag: "exp", Until: until}, until.Add(23 * time.Hour), true},
+		{Directive{Tag: "exp", Until: until}, until.AddDate(0, 0, 1), false},
+	}
+	for _, tt := range tests {
+		if got := tt.d.Deployed(tt.now); got != tt.want {
+			t.Errorf("%+v deployed at %s = %v, want %v", tt.d, tt.now, got, tt.want)
+		}
+	}
+}
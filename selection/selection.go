@@ -0,0 +1,68 @@
+// Package selection decides which change requests are included in the experimental build,
+// the same way for all the forges: by labels or by deployment directives in comments.
+package selection
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Policy selects the change requests having all the labels OR having the latest deployment
+// directive in comments ("deployment: tag", "deployment: no-tag") for any of the tags.
+// Empty policy selects all change requests.
+type Policy struct {
+	Labels []string
+	Tags   []string
+}
+
+// All returns true if the policy selects all change requests
+func (p Policy) All() bool {
+	return len(p.Labels) == 0 && len(p.Tags) == 0
+}
+
+// Select decides about the change request with the labels,
+// comments (from the oldest) are loaded only if the labels do not decide.
+// The directive is returned if the change request is selected by it.
+// Malformed directives are logged and skipped.
+func (p Policy) Select(labels []string, comments func() ([]string, error)) (bool, *Directive, error) {
+	if p.All() {
+		return true, nil, nil
+	}
+	if len(p.Labels) > 0 && hasAll(labels, p.Labels) {
+		return true, nil, nil
+	}
+	if len(p.Tags) == 0 {
+		return false, nil, nil
+	}
+	texts, err := comments()
+	if err != nil {
+		return false, nil, err
+	}
+	d, err := LatestDirective(texts, p.Tags)
+	if err != nil {
+		slog.Warn("Ignoring malformed deployment directives", "error", err)
+	}
+	if d == nil || !d.Deployed(time.Now()) {
+		return false, nil, nil
+	}
+	return true, d, nil
+}
+
+func hasAll(labels, required []string) bool {
+	for _, l := range required {
+		if !slices.Contains(labels, l) {
+			return false
+		}
+	}
+	return true
+}
+
+// TestComment returns (decided, deployed) for the first valid directive of the comment for any of the tags
+func TestComment(comment string, tags []string) (bool, bool) {
+	d, _ := LatestDirective([]string{comment}, tags)
+	if d == nil {
+		return false, false
+	}
+	return true, d.Deployed(time.Now())
+}